@@ -0,0 +1,32 @@
+package funchain
+
+// Future is the pending outcome of a chain started with DoAsync.
+type Future struct {
+	done   chan struct{}
+	result []interface{}
+	err    error
+}
+
+// DoAsync executes the function chain like Do in a new goroutine and returns at once.
+// Hooks and defers run in that goroutine. The out pointers are only written before the run completes,
+// so they must not be read before Wait returned or Done is closed.
+func (fc *FunChain) DoAsync(out ...interface{}) *Future {
+	f := &Future{done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		f.result, f.err = fc.Do(out...)
+	}()
+	return f
+}
+
+// Wait waits for the run to complete and returns the results of Do.
+// It can be called any number of times and from several goroutines.
+func (f *Future) Wait() ([]interface{}, error) {
+	<-f.done
+	return f.result, f.err
+}
+
+// Done returns a channel closed when the run completed, e.g. to wait in a select statement.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
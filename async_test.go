@@ -0,0 +1,50 @@
+package funchain
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDoAsync(t *testing.T) {
+	release := make(chan struct{})
+	var (
+		result   int
+		deferred bool
+	)
+	f := New(func() int {
+		<-release
+		return 42
+	}).Defer(func() {
+		deferred = true
+	}).DoAsync(&result)
+
+	// 在等待结果的同时做其他工作
+	select {
+	case <-f.Done():
+		t.Fatal("the chain shouldn't complete before the function returns")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r, err := f.Wait(); err != nil || r[0] != 42 {
+				t.Errorf("unexpected outcome: %v, %v", r, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if result != 42 || !deferred {
+		t.Fatalf("unexpected state: result=%d, deferred=%v", result, deferred)
+	}
+
+	errFailed := errors.New("failed")
+	if _, err := New(func() error { return errFailed }).DoAsync().Wait(); !errors.Is(err, errFailed) {
+		t.Fatalf("expected the chain error, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package funchain
+
+import (
+	"strconv"
+	"testing"
+)
+
+// BenchmarkDo measures a chain executed repeatedly, the hot path of execFunc.
+func BenchmarkDo(b *testing.B) {
+	fc := New(func() (int, error) {
+		return 21, nil
+	}, double, strconv.Itoa, func(s string) (string, error) {
+		return s + "!", nil
+	})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := fc.Do(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExecFunc measures a single direct function call with error extraction.
+func BenchmarkExecFunc(b *testing.B) {
+	fn := func(n int, s string) (string, error) {
+		return s, nil
+	}
+	args := []interface{}{1, "a"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := execFunc(fn, args, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
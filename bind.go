@@ -0,0 +1,76 @@
+package funchain
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Bind adds a step that packs the current arguments into the struct ptr points to and passes ptr
+// to the next function, so that a step can work with named fields in the middle of a positional chain.
+// Arguments are assigned to the exported fields in declaration order, fields without a corresponding
+// argument are set to their zero value.
+// If the next function returns no values (apart from an error), the possibly modified fields are unpacked
+// back into positional arguments for the function after it; otherwise its return values are passed on as usual.
+// ptr is shared by all runs of the chain, so a chain using Bind must not be executed concurrently.
+func (fc *FunChain) Bind(ptr interface{}) *FunChain {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fc
+	}
+	fc.addStep(&step{kind: "Bind", bind: ptr, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		if err := packStruct(ptr, args); err != nil {
+			return nil, err
+		}
+		return []interface{}{ptr}, nil
+	}})
+	return fc
+}
+
+// structFields returns the exported fields of the struct ptr points to, in declaration order.
+func structFields(ptr interface{}) []reflect.Value {
+	v := reflect.ValueOf(ptr).Elem()
+	fields := make([]reflect.Value, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).IsExported() {
+			fields = append(fields, v.Field(i))
+		}
+	}
+	return fields
+}
+
+// packStruct assigns args to the exported fields of the struct ptr points to.
+func packStruct(ptr interface{}, args []interface{}) error {
+	v := reflect.ValueOf(ptr).Elem()
+	fields := structFields(ptr)
+	if len(args) > len(fields) {
+		return fmt.Errorf("cannot bind %d arguments to %s which has %d exported fields", len(args), v.Type(), len(fields))
+	}
+	v.Set(reflect.Zero(v.Type()))
+	exported := 0
+	for i := 0; i < v.NumField() && exported < len(args); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		arg := args[exported]
+		exported++
+		if arg == nil {
+			continue
+		}
+		if !reflect.TypeOf(arg).AssignableTo(field.Type) {
+			return fmt.Errorf("cannot bind argument %d (%T) to field %s.%s (%s)", exported-1, arg, v.Type(), field.Name, field.Type)
+		}
+		v.Field(i).Set(reflect.ValueOf(arg))
+	}
+	return nil
+}
+
+// unpackStruct returns the exported fields of the struct ptr points to as positional arguments.
+func unpackStruct(ptr interface{}) []interface{} {
+	fields := structFields(ptr)
+	args := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		args = append(args, field.Interface())
+	}
+	return args
+}
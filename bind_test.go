@@ -0,0 +1,45 @@
+package funchain
+
+import (
+	"strings"
+	"testing"
+)
+
+type user struct {
+	Name   string
+	Age    int
+	secret string
+	Admin  bool
+}
+
+func TestBind(t *testing.T) {
+	var (
+		u       user
+		summary string
+	)
+	_, err := New(func() (string, int) {
+		return "alice", 30
+	}).Bind(&u).Then(func(u *user) {
+		u.Name = strings.ToUpper(u.Name)
+		u.Admin = u.Age >= 18
+	}).Then(func(name string, age int, admin bool) string {
+		if admin {
+			return name + " (admin)"
+		}
+		return name
+	}).Do(&summary)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if summary != "ALICE (admin)" {
+		t.Fatalf("unexpected result: %q", summary)
+	}
+
+	// 类型不匹配时错误信息指出字段名
+	_, err = New(func() (string, string) {
+		return "bob", "thirty"
+	}).Bind(&u).Do()
+	if err == nil || !strings.Contains(err.Error(), "field funchain.user.Age") {
+		t.Fatalf("expected error naming the mismatched field, got %v", err)
+	}
+}
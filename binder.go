@@ -0,0 +1,139 @@
+package funchain
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Binder binds the results of a run to the out arguments of Do, see WithBinder.
+// Bind is called once per run, after the chain completed, possibly with partial failures. It must set
+// the results to the targets in out and return how many targets were populated, which DoBound reports.
+// An error returned by Bind is returned by Do if the chain itself succeeded.
+// Bind may be called concurrently when the chain is executed concurrently.
+type Binder interface {
+	Bind(results []interface{}, out []interface{}) (bound int, err error)
+}
+
+// BinderFunc adapts a function to the Binder interface.
+type BinderFunc func(results []interface{}, out []interface{}) (bound int, err error)
+
+// Bind calls f.
+func (f BinderFunc) Bind(results []interface{}, out []interface{}) (int, error) {
+	return f(results, out)
+}
+
+// WithBinder sets how the results are bound to the out arguments of Do, the default is PositionalBinder.
+func (fc *FunChain) WithBinder(b Binder) *FunChain {
+	return fc.Configure(WithBinder(b))
+}
+
+// WithBinder returns an Option doing the same as FunChain.WithBinder.
+func WithBinder(b Binder) Option {
+	return func(fc *FunChain) {
+		fc.binder = b
+	}
+}
+
+// bind binds the results to out with the chain's binder.
+func (fc *FunChain) bind(results []interface{}, out []interface{}) (int, error) {
+	if fc.binder == nil {
+		return PositionalBinder.Bind(results, out)
+	}
+	return fc.binder.Bind(results, out)
+}
+
+// PositionalBinder sets the i-th result to the pointer out[i], out targets that can't be set are skipped.
+// A result that can't be assigned to its target fails binding with an error instead of panicking.
+var PositionalBinder Binder = BinderFunc(bindResults)
+
+// TypeBinder sets to each pointer in out the first result assignable to the type it points to,
+// regardless of positions, each result is used at most once.
+var TypeBinder Binder = BinderFunc(func(results []interface{}, out []interface{}) (int, error) {
+	used := make([]bool, len(results))
+	bound := 0
+	for _, o := range out {
+		dst := reflect.ValueOf(o)
+		if dst.Kind() != reflect.Ptr || dst.IsNil() {
+			return bound, fmt.Errorf("cannot bind to %T, a non-nil pointer is required", o)
+		}
+		dst = dst.Elem()
+		for i, r := range results {
+			if used[i] || r == nil || !reflect.TypeOf(r).AssignableTo(dst.Type()) {
+				continue
+			}
+			dst.Set(reflect.ValueOf(r))
+			used[i] = true
+			bound++
+			break
+		}
+	}
+	return bound, nil
+})
+
+// StructBinder assigns the results to the exported fields of the struct out[0] points to, in declaration
+// order like Bind. The number of bound targets is the number of fields set.
+var StructBinder Binder = BinderFunc(func(results []interface{}, out []interface{}) (int, error) {
+	if len(out) == 0 {
+		return 0, nil
+	}
+	v := reflect.ValueOf(out[0])
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return 0, fmt.Errorf("cannot bind to %T, a pointer to a struct is required", out[0])
+	}
+	if err := packStruct(out[0], results); err != nil {
+		return 0, err
+	}
+	return len(results), nil
+})
+
+// MapBinder returns a Binder storing the i-th result under keys[i] in the map[string]interface{} out[0],
+// results without a key are ignored.
+func MapBinder(keys ...string) Binder {
+	return BinderFunc(func(results []interface{}, out []interface{}) (int, error) {
+		if len(out) == 0 {
+			return 0, nil
+		}
+		m, ok := out[0].(map[string]interface{})
+		if !ok || m == nil {
+			return 0, fmt.Errorf("cannot bind to %T, a non-nil map[string]interface{} is required", out[0])
+		}
+		bound := 0
+		for i, key := range keys {
+			if i >= len(results) {
+				break
+			}
+			m[key] = results[i]
+			bound++
+		}
+		return bound, nil
+	})
+}
+
+// DoInto executes the function chain like Do and sets the results at the given indexes to the pointers
+// they map to, e.g. {2: &x} to only get the third result without placeholders for the first two.
+// Like with Do, the results are also set when the chain completed despite partial failures.
+// The chain's binder is not used. An error is returned if an index is out of range, a target is not a
+// non-nil pointer or a result can't be assigned to its target, see PositionalBinder.
+func (fc *FunChain) DoInto(targets map[int]interface{}) ([]interface{}, error) {
+	result, err := fc.Do()
+	if _, partial := err.(*partialError); err != nil && !partial {
+		return result, err
+	}
+	indexes := make([]int, 0, len(targets))
+	for i := range targets {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		if i < 0 || i >= len(result) {
+			return result, fmt.Errorf("result index %d out of range, the chain returned %d results", i, len(result))
+		}
+		if err := bindResult(i, result[i], targets[i]); err == errNotSettable {
+			return result, fmt.Errorf("target of result %d must be a non-nil pointer, got %T", i, targets[i])
+		} else if err != nil {
+			return result, err
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,80 @@
+package funchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBinders(t *testing.T) {
+	produce := func() (int, string) {
+		return 7, "seven"
+	}
+
+	// 默认按位置绑定
+	var (
+		n int
+		s string
+	)
+	if _, err := New(produce).Do(&n, &s); err != nil || n != 7 || s != "seven" {
+		t.Fatalf("positional binding failed: n=%d, s=%q, err=%v", n, s, err)
+	}
+
+	n, s = 0, ""
+	bound, _, err := New(produce).WithBinder(TypeBinder).DoBound(&s, &n)
+	if err != nil || bound != 2 || n != 7 || s != "seven" {
+		t.Fatalf("type binding failed: bound=%d, n=%d, s=%q, err=%v", bound, n, s, err)
+	}
+
+	var target struct {
+		Num  int
+		Name string
+	}
+	if _, err := New(produce).WithBinder(StructBinder).Do(&target); err != nil || target.Num != 7 || target.Name != "seven" {
+		t.Fatalf("struct binding failed: %+v, err=%v", target, err)
+	}
+	if _, err := New(produce).WithBinder(StructBinder).Do(&n); err == nil {
+		t.Fatal("expected error when binding to a non-struct")
+	}
+
+	m := map[string]interface{}{}
+	if _, err := New(produce).WithBinder(MapBinder("num", "name")).Do(m); err != nil || m["num"] != 7 || m["name"] != "seven" {
+		t.Fatalf("map binding failed: %v, err=%v", m, err)
+	}
+
+	// 自定义 Binder
+	custom := BinderFunc(func(results []interface{}, out []interface{}) (int, error) {
+		*out[0].(*string) = results[1].(string) + "!"
+		return 1, nil
+	})
+	var custom1 string
+	if _, err := New(produce).WithBinder(custom).Do(&custom1); err != nil || custom1 != "seven!" {
+		t.Fatalf("custom binding failed: %q, err=%v", custom1, err)
+	}
+}
+
+func TestDoInto(t *testing.T) {
+	fc := New(func() (int, string, bool, float64) {
+		return 1, "a", true, 2.5
+	})
+	var (
+		s string
+		f float64
+	)
+	if _, err := fc.DoInto(map[int]interface{}{1: &s, 3: &f}); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if s != "a" || f != 2.5 {
+		t.Fatalf("unexpected state: s=%q, f=%v", s, f)
+	}
+
+	var n int
+	if _, err := fc.DoInto(map[int]interface{}{4: &n}); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected range error, got %v", err)
+	}
+	if _, err := fc.DoInto(map[int]interface{}{1: &n}); err == nil || !strings.Contains(err.Error(), "cannot assign result 1") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+	if _, err := fc.DoInto(map[int]interface{}{0: n}); err == nil || !strings.Contains(err.Error(), "non-nil pointer") {
+		t.Fatalf("expected pointer error, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+package funchain
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is the cached result of a chain run.
+type CacheEntry struct {
+	Result []interface{}
+	// Expires is the time after which the entry is stale, the zero time means never.
+	Expires time.Time
+}
+
+// Cache stores the results of chain runs, see WithResultCache.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (entry CacheEntry, ok bool)
+	Set(key string, entry CacheEntry)
+}
+
+// MapCache is a Cache backed by a map, stale entries are only replaced, never evicted.
+type MapCache struct {
+	mu      sync.RWMutex
+	entries map[string]CacheEntry
+}
+
+// NewMapCache creates an empty MapCache.
+func NewMapCache() *MapCache {
+	return &MapCache{entries: make(map[string]CacheEntry)}
+}
+
+// Get returns the entry stored under key.
+func (c *MapCache) Get(key string) (CacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// Set stores entry under key.
+func (c *MapCache) Set(key string, entry CacheEntry) {
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// LRUCache is a Cache holding at most a fixed number of entries, evicting the least recently used one.
+type LRUCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// lruEntry is an element of the recency list of an LRUCache.
+type lruEntry struct {
+	key   string
+	entry CacheEntry
+}
+
+// NewLRUCache creates an empty LRUCache holding at most size entries, at least one.
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// Get returns the entry stored under key and marks it as recently used.
+func (c *LRUCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry if the cache is full.
+func (c *LRUCache) Set(key string, entry CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, entry: entry})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Memoize caches the final results of the chain keyed by its seed arguments like WithResultCache,
+// in an LRUCache of the given size, or in a MapCache without limit if size is 0.
+// ttl: how long results stay fresh according to the chain's clock, 0 means forever.
+// Use WithCacheKey for seeds that can't be used as key, such as functions, or should be keyed differently.
+func (fc *FunChain) Memoize(size int, ttl time.Duration) *FunChain {
+	return fc.Configure(Memoize(size, ttl))
+}
+
+// Memoize returns an Option doing the same as FunChain.Memoize.
+// Every chain the Option is applied to gets its own cache.
+func Memoize(size int, ttl time.Duration) Option {
+	return func(fc *FunChain) {
+		var store Cache = NewMapCache()
+		if size > 0 {
+			store = NewLRUCache(size)
+		}
+		fc.cache = store
+		fc.cacheTTL = ttl
+	}
+}
+
+// WithCacheKey sets the function deriving the cache key from the seed arguments when results are cached,
+// see WithResultCache. Seeds for which key returns an error fail the run with that error.
+// nil restores the default key based on the types and values of the seed.
+func (fc *FunChain) WithCacheKey(key func(seed []interface{}) (string, error)) *FunChain {
+	return fc.Configure(WithCacheKey(key))
+}
+
+// WithCacheKey returns an Option doing the same as FunChain.WithCacheKey.
+func WithCacheKey(key func(seed []interface{}) (string, error)) Option {
+	return func(fc *FunChain) {
+		fc.cacheKey = key
+	}
+}
+
+// WithResultCache caches the final results of the chain keyed by its seed arguments (see InputFunc),
+// so that running the chain again with the same seed returns the cached results without executing
+// any function or hook. Only successful runs are cached. It suits pure end-to-end pipelines.
+// store: where the results are kept, e.g. NewMapCache().
+// ttl: how long results stay fresh according to the chain's clock, 0 means forever.
+// The key is derived from the types and values of the seed arguments, unexported fields included, unless
+// WithCacheKey is used. A run whose seed can't be encoded, e.g. because it contains a function, a channel
+// or cyclic data, fails with an error.
+// The cache keeps its own copy of the result slice, but values the results point to, such as the elements
+// of a slice result, are shared between the runs and must not be modified.
+func (fc *FunChain) WithResultCache(store Cache, ttl time.Duration) *FunChain {
+	return fc.Configure(WithResultCache(store, ttl))
+}
+
+// WithResultCache returns an Option doing the same as FunChain.WithResultCache.
+func WithResultCache(store Cache, ttl time.Duration) Option {
+	return func(fc *FunChain) {
+		fc.cache = store
+		fc.cacheTTL = ttl
+	}
+}
+
+// executeCached returns the cached results for seed, or runs the chain and caches its results.
+func (fc *FunChain) executeCached(rs *runState, seed []interface{}, out []interface{}) (boundCount int, result []interface{}, err error) {
+	keyFunc := fc.cacheKey
+	if keyFunc == nil {
+		keyFunc = seedKey
+	}
+	key, err := keyFunc(seed)
+	if err != nil {
+		return 0, nil, err
+	}
+	if entry, ok := fc.cache.Get(key); ok && (entry.Expires.IsZero() || fc.now().Before(entry.Expires)) {
+		// 返回副本，调用方修改结果不影响缓存
+		result = append([]interface{}(nil), entry.Result...)
+		boundCount, err = fc.bind(result, out)
+		return boundCount, result, err
+	}
+	boundCount, result, err = fc.execute(rs, 0, seed, out)
+	if err == nil {
+		entry := CacheEntry{Result: append([]interface{}(nil), result...)}
+		if fc.cacheTTL > 0 {
+			entry.Expires = fc.now().Add(fc.cacheTTL)
+		}
+		fc.cache.Set(key, entry)
+	}
+	return boundCount, result, err
+}
+
+// seedKey derives a cache key from the seed arguments.
+// The key covers the type and the whole value of every argument, unexported fields included, so that
+// different seeds never share a key.
+func seedKey(seed []interface{}) (string, error) {
+	h := sha256.New()
+	for i, arg := range seed {
+		if err := writeKey(h, reflect.ValueOf(arg), map[uintptr]bool{}); err != nil {
+			return "", fmt.Errorf("seed argument %d (%T) can't be used as cache key: %w", i, arg, err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeKey writes an unambiguous encoding of the type and the value of v to w.
+// seen contains the pointers being encoded, to stop at cyclic data.
+func writeKey(w io.Writer, v reflect.Value, seen map[uintptr]bool) error {
+	if !v.IsValid() {
+		_, err := io.WriteString(w, "nil;")
+		return err
+	}
+	fmt.Fprintf(w, "%s:", v.Type())
+	switch v.Kind() {
+	case reflect.Bool:
+		fmt.Fprintf(w, "%t;", v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(w, "%d;", v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		fmt.Fprintf(w, "%d;", v.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(w, "%b;", v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		fmt.Fprintf(w, "%b;", v.Complex())
+	case reflect.String:
+		fmt.Fprintf(w, "%q;", v.String())
+	case reflect.Ptr:
+		if v.IsNil() {
+			_, err := io.WriteString(w, "nil;")
+			return err
+		}
+		if seen[v.Pointer()] {
+			return errors.New("cyclic value")
+		}
+		seen[v.Pointer()] = true
+		defer delete(seen, v.Pointer())
+		return writeKey(w, v.Elem(), seen)
+	case reflect.Interface:
+		return writeKey(w, v.Elem(), seen)
+	case reflect.Struct:
+		fmt.Fprintf(w, "%d{", v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			if err := writeKey(w, v.Field(i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			_, err := io.WriteString(w, "nil;")
+			return err
+		}
+		fmt.Fprintf(w, "%d[", v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := writeKey(w, v.Index(i), seen); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			_, err := io.WriteString(w, "nil;")
+			return err
+		}
+		// 按键的编码排序，使相同的 map 得到相同的键
+		entries := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			var entry strings.Builder
+			if err := writeKey(&entry, iter.Key(), seen); err != nil {
+				return err
+			}
+			if err := writeKey(&entry, iter.Value(), seen); err != nil {
+				return err
+			}
+			entries = append(entries, entry.String())
+		}
+		sort.Strings(entries)
+		fmt.Fprintf(w, "%d{%s", len(entries), strings.Join(entries, ""))
+	default:
+		return fmt.Errorf("values of kind %s are not supported", v.Kind())
+	}
+	return nil
+}
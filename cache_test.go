@@ -0,0 +1,228 @@
+package funchain
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultCache(t *testing.T) {
+	var (
+		calls  int
+		seed   = []interface{}{2, "x"}
+		result string
+	)
+	clock := &fakeClock{now: time.Now()}
+	fc := New(func(n int, s string) string {
+		calls++
+		return strings.Repeat(s, n)
+	}).InputFunc(func() []interface{} {
+		return seed
+	}).WithClock(clock).WithResultCache(NewMapCache(), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		result = ""
+		if _, err := fc.Do(&result); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if result != "xx" {
+			t.Fatalf("unexpected result: %q", result)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("function should run once for the same seed, ran %d times", calls)
+	}
+
+	// 不同的种子参数使用不同的缓存
+	seed = []interface{}{3, "x"}
+	if _, err := fc.Do(&result); err != nil || result != "xxx" || calls != 2 {
+		t.Fatalf("unexpected state: result=%q, calls=%d, err=%v", result, calls, err)
+	}
+
+	// 过期后重新执行
+	clock.Advance(2 * time.Minute)
+	if _, err := fc.Do(); err != nil || calls != 3 {
+		t.Fatalf("stale entry should be refreshed: calls=%d, err=%v", calls, err)
+	}
+
+	seed = []interface{}{func() {}}
+	if _, err := fc.Do(); err == nil || !strings.Contains(err.Error(), "seed argument 0") {
+		t.Fatalf("expected error for non-encodable seed, got %v", err)
+	}
+}
+
+func TestResultCacheSeedKey(t *testing.T) {
+	// 只有未导出字段的结构体 JSON 编码相同，但键必须不同
+	type point struct {
+		x, y int
+	}
+	var seed interface{}
+	fc := New(func(p point) int {
+		return p.x + p.y
+	}).InputFunc(func() []interface{} {
+		return []interface{}{seed}
+	}).WithResultCache(NewMapCache(), 0)
+	for _, p := range []point{{1, 2}, {3, 4}, {1, 2}} {
+		seed = p
+		var sum int
+		if _, err := fc.Do(&sum); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if sum != p.x+p.y {
+			t.Fatalf("seed %v: got the cached result of another seed: %d", p, sum)
+		}
+	}
+
+	keys := make(map[string]interface{})
+	for _, v := range []interface{}{
+		nil, 0, int64(0), "", "a", []string{"a", "b"}, []string{"ab"}, []string(nil), []string{},
+		map[string]int{"a": 1, "b": 2}, map[string]int{"a": 2, "b": 1}, &point{1, 2}, point{1, 2},
+	} {
+		key, err := seedKey([]interface{}{v})
+		if err != nil {
+			t.Fatalf("%#v: unexpected error: %v", v, err)
+		}
+		if other, ok := keys[key]; ok {
+			t.Fatalf("%#v and %#v share a key", v, other)
+		}
+		keys[key] = v
+	}
+	// 相同的 map 得到相同的键
+	a, _ := seedKey([]interface{}{map[string]int{"a": 1, "b": 2, "c": 3}})
+	b, _ := seedKey([]interface{}{map[string]int{"c": 3, "b": 2, "a": 1}})
+	if a != b {
+		t.Fatal("equal maps should share a key")
+	}
+	type node struct {
+		next *node
+	}
+	cyclic := &node{}
+	cyclic.next = cyclic
+	if _, err := seedKey([]interface{}{cyclic}); err == nil {
+		t.Fatal("expected error for cyclic seed")
+	}
+}
+
+func TestResultCacheCopy(t *testing.T) {
+	fc := New(func() (int, string) {
+		return 1, "a"
+	}).WithResultCache(NewMapCache(), 0)
+	for i := 0; i < 3; i++ {
+		result, err := fc.Do()
+		if err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if result[0] != 1 || result[1] != "a" {
+			t.Fatalf("run %d: cached result modified by the caller: %v", i, result)
+		}
+		// 修改返回的切片不影响缓存
+		result[0], result[1] = 2, "b"
+	}
+}
+
+func TestMemoize(t *testing.T) {
+	var (
+		calls  int
+		hooked int
+		seed   []interface{}
+	)
+	fc := New(func(n int) int {
+		calls++
+		return n * n
+	}).Before(func(input []interface{}) {
+		hooked++
+	}).InputFunc(func() []interface{} {
+		return seed
+	}).Memoize(2, 0)
+	run := func(n int) int {
+		seed = []interface{}{n}
+		var result int
+		if _, err := fc.Do(&result); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		return result
+	}
+	for i := 0; i < 3; i++ {
+		if run(3) != 9 || run(4) != 16 {
+			t.Fatal("unexpected result")
+		}
+	}
+	if calls != 2 || hooked != 2 {
+		t.Fatalf("functions and hooks should run once per input: calls=%d, hooked=%d", calls, hooked)
+	}
+	// 超出容量时淘汰最久未使用的结果
+	run(5)
+	run(3)
+	if calls != 4 {
+		t.Fatalf("least recently used result should be evicted: calls=%d", calls)
+	}
+
+	// 自定义键支持无法编码的参数
+	type request struct {
+		id       int
+		callback func()
+	}
+	calls = 0
+	keyed := New(func(r request) int {
+		calls++
+		return r.id
+	}).InputFunc(func() []interface{} {
+		return []interface{}{request{id: 1, callback: func() {}}}
+	}).Memoize(0, time.Minute).WithCacheKey(func(seed []interface{}) (string, error) {
+		return fmt.Sprint(seed[0].(request).id), nil
+	})
+	for i := 0; i < 2; i++ {
+		if _, err := keyed.Do(); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("function should run once for the same key, ran %d times", calls)
+	}
+}
+
+func TestMemoizeSeedKeyAndCopy(t *testing.T) {
+	type point struct {
+		x, y int
+	}
+	var (
+		seed  point
+		calls int
+	)
+	fc := New(func(p point) []interface{} {
+		calls++
+		return []interface{}{p.x, p.y}
+	}).InputFunc(func() []interface{} {
+		return []interface{}{seed}
+	}).Memoize(4, 0)
+	for i, p := range []point{{1, 2}, {3, 4}, {1, 2}, {3, 4}} {
+		seed = p
+		result, err := fc.Do()
+		if err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if got := result[0].([]interface{}); got[0] != p.x || got[1] != p.y {
+			t.Fatalf("run %d: got the result of another seed: %v", i, got)
+		}
+		// 修改返回的切片不影响 LRU 缓存
+		result[0] = nil
+	}
+	if calls != 2 {
+		t.Fatalf("function should run once per seed, ran %d times", calls)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", CacheEntry{Result: []interface{}{1}})
+	c.Set("b", CacheEntry{Result: []interface{}{2}})
+	c.Get("a")
+	c.Set("c", CacheEntry{Result: []interface{}{3}})
+	if _, ok := c.Get("b"); ok || c.Len() != 2 {
+		t.Fatalf("expected b to be evicted, len=%d", c.Len())
+	}
+	if entry, ok := c.Get("a"); !ok || entry.Result[0] != 1 {
+		t.Fatal("expected a to be kept")
+	}
+}
@@ -0,0 +1,164 @@
+package funchain
+
+import (
+	"context"
+	"sync"
+)
+
+// DoToChan executes the function chain with the context ctx like DoContext and sends each result value to
+// ch in order, closing ch when done, even if the chain fails. It suits producer/consumer setups where
+// results are consumed from a channel.
+// ctx: the chain stops once ctx is done, and so does sending, with ctx.Err(), so a full channel never
+// blocks forever.
+// Only the results of the last function are sent, see DoStepsToChan for the results of every step, or Stream
+// to push a stream of inputs through the steps.
+func (fc *FunChain) DoToChan(ctx context.Context, ch chan<- interface{}) error {
+	defer close(ch)
+	result, err := fc.DoContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, v := range result {
+		select {
+		case ch <- v:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// StepOutput is the output of a step sent by DoStepsToChan.
+type StepOutput struct {
+	// Index is the zero-based index of the step in the chain.
+	Index int
+	// Name is the name of the step as reported by StepName.
+	Name string
+	// Output holds the values returned by the step.
+	Output []interface{}
+}
+
+// DoStepsToChan executes the function chain with the context ctx like DoToChan, but sends the output of every
+// step of the root chain to ch as soon as the step succeeded, closing ch when done, even if the chain fails.
+// Steps that fail, that are skipped by When or that run after a failure, such as ThenAlways steps, send
+// nothing. The result cache, if set, isn't used.
+// ctx: the chain stops once ctx is done, and so does sending, with ctx.Err(), so a full channel never
+// blocks forever.
+func (fc *FunChain) DoStepsToChan(ctx context.Context, ch chan<- StepOutput) error {
+	defer close(ch)
+	snap := fc.snapshot()
+	rs := newRunState(snap)
+	rs.ctx = ctx
+	var interrupted bool
+	rs.onStepDone = func(i int, output []interface{}) {
+		select {
+		case ch <- StepOutput{Index: i, Name: snap.stepName(snap.steps[i]), Output: output}:
+		case <-ctx.Done():
+			interrupted = true
+		}
+	}
+	_, _, err := snap.execute(rs, 0, snap.seedArgs(), nil)
+	if err == nil && interrupted {
+		// 最后一步的结果未能发送
+		err = ctx.Err()
+	}
+	return err
+}
+
+// WithStreamBuffer sets the buffer size of the channels between the steps in Stream, so that each step can
+// run ahead of the next one by n elements, trading memory for throughput.
+// The default 0 means unbuffered channels: a step only takes the next element once the following step
+// accepted its previous result (strict backpressure).
+// Buffering doesn't change error handling: when a step fails or ctx is done the pipeline stops and the
+// elements still sitting in the buffers are dropped without being processed further.
+func (fc *FunChain) WithStreamBuffer(n int) *FunChain {
+	return fc.Configure(WithStreamBuffer(n))
+}
+
+// WithStreamBuffer returns an Option doing the same as FunChain.WithStreamBuffer.
+func WithStreamBuffer(n int) Option {
+	return func(fc *FunChain) {
+		if n < 0 {
+			n = 0
+		}
+		fc.streamBuffer = n
+	}
+}
+
+// Stream runs the chain as a pipeline over a stream of inputs: every element received from in is the
+// arguments of the first function, and the results of the last function for each element are sent to out
+// in order. Every step runs in its own goroutine connected to the next one by a channel, so several
+// elements are processed by different steps at the same time. Hooks, defers and events are not used in
+// this mode.
+// Stream returns once in is closed and all elements went through, or as soon as a step fails or ctx is
+// done, in which case the error is returned and the elements in flight are dropped. out is always closed.
+// Senders on in should also watch ctx since in is no longer read after a failure.
+func (fc *FunChain) Stream(ctx context.Context, in <-chan []interface{}, out chan<- []interface{}) error {
+	defer close(out)
+	snap := fc.snapshot()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	src := in
+	for _, st := range snap.steps {
+		dst := make(chan []interface{}, snap.streamBuffer)
+		wg.Add(1)
+		go func(st *step, src <-chan []interface{}, dst chan<- []interface{}) {
+			defer wg.Done()
+			defer close(dst)
+			rs := newRunState(snap)
+			rs.ctx = runCtx
+			rs.active[snap.self()] = true
+			rs.depth = 1
+			for {
+				var (
+					args []interface{}
+					ok   bool
+				)
+				select {
+				case args, ok = <-src:
+					if !ok {
+						return
+					}
+				case <-runCtx.Done():
+					return
+				}
+				result, err := args, error(nil)
+				if st.cond == nil || snap.runsStep(st, args) {
+					result, err = snap.execStep(rs, st, args)
+				}
+				if err != nil {
+					fail(err)
+					return
+				}
+				select {
+				case dst <- result:
+				case <-runCtx.Done():
+					return
+				}
+			}
+		}(st, src, dst)
+		src = dst
+	}
+	for result := range src {
+		select {
+		case out <- result:
+		case <-runCtx.Done():
+		}
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,214 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDoToChan(t *testing.T) {
+	ch := make(chan interface{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(func() (int, string) {
+			return 1, "a"
+		}).DoToChan(context.Background(), ch)
+	}()
+	var values []interface{}
+	for v := range ch {
+		values = append(values, v)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{1, "a"}) {
+		t.Fatalf("unexpected values: %v", values)
+	}
+
+	// 没有消费者时取消 context 不会永久阻塞
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch = make(chan interface{})
+	err := New(func() int {
+		return 1
+	}).DoToChan(ctx, ch)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed")
+	}
+	// context 传给函数，取消后函数链停止
+	var (
+		got  interface{}
+		runs int
+	)
+	ctx = context.WithValue(context.Background(), ctxKey{}, "v")
+	ctx, cancel = context.WithCancel(ctx)
+	ch = make(chan interface{}, 1)
+	err = New(func(ctx context.Context) int {
+		got = ctx.Value(ctxKey{})
+		cancel()
+		return 1
+	}, func(n int) int {
+		runs++
+		return n
+	}).DoToChan(ctx, ch)
+	if got != "v" {
+		t.Fatalf("function didn't receive ctx, got %v", got)
+	}
+	if !errors.Is(err, context.Canceled) || runs != 0 {
+		t.Fatalf("chain should stop on cancellation: err=%v, runs=%d", err, runs)
+	}
+}
+
+func TestDoToChanCancelBlocked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{})
+	go func() {
+		<-ran
+		// 等待 DoToChan 阻塞在发送上
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	// 没有人读取 channel，取消后 DoToChan 返回并关闭 channel
+	ch := make(chan interface{})
+	err := New(func() int {
+		close(ran)
+		return 1
+	}).DoToChan(ctx, ch)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed")
+	}
+}
+
+func TestDoStepsToChan(t *testing.T) {
+	ch := make(chan StepOutput)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- New(func() int {
+			return 1
+		}).When(func(input []interface{}) bool {
+			return false
+		}, func(n int) int {
+			return n * 10
+		}).ThenNamed("pair", func(n int) (int, string) {
+			return n + 1, "a"
+		}).DoStepsToChan(context.Background(), ch)
+	}()
+	var outputs []StepOutput
+	for o := range ch {
+		outputs = append(outputs, o)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 跳过的步骤不发送结果
+	if len(outputs) != 2 {
+		t.Fatalf("expected 2 outputs, got %v", outputs)
+	}
+	if outputs[0].Index != 0 || !reflect.DeepEqual(outputs[0].Output, []interface{}{1}) {
+		t.Fatalf("unexpected output of the first step: %+v", outputs[0])
+	}
+	if outputs[1].Index != 2 || outputs[1].Name != "pair" || !reflect.DeepEqual(outputs[1].Output, []interface{}{2, "a"}) {
+		t.Fatalf("unexpected output of the last step: %+v", outputs[1])
+	}
+
+	// 失败的步骤不发送结果，错误原样返回
+	errBoom := errors.New("boom")
+	ch = make(chan StepOutput, 4)
+	err := New(func() int {
+		return 1
+	}, func(n int) error {
+		return errBoom
+	}).DoStepsToChan(context.Background(), ch)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom error, got %v", err)
+	}
+	if o := <-ch; o.Index != 0 {
+		t.Fatalf("unexpected output: %+v", o)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed")
+	}
+
+	// 发送阻塞时取消 context，函数链停止
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{})
+	go func() {
+		<-ran
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	var runs int
+	ch = make(chan StepOutput)
+	err = New(func() int {
+		close(ran)
+		return 1
+	}, func(n int) int {
+		runs++
+		return n
+	}).DoStepsToChan(ctx, ch)
+	if !errors.Is(err, context.Canceled) || runs != 0 {
+		t.Fatalf("chain should stop on cancellation: err=%v, runs=%d", err, runs)
+	}
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed")
+	}
+}
+
+func TestStream(t *testing.T) {
+	for _, buffer := range []int{0, 4} {
+		fc := New(func(n int) int {
+			return n * 2
+		}, func(n int) (int, string) {
+			return n + 1, "ok"
+		}).WithStreamBuffer(buffer)
+		in := make(chan []interface{})
+		out := make(chan []interface{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- fc.Stream(context.Background(), in, out)
+		}()
+		go func() {
+			for i := 0; i < 5; i++ {
+				in <- []interface{}{i}
+			}
+			close(in)
+		}()
+		var results []int
+		for r := range out {
+			results = append(results, r[0].(int))
+		}
+		if err := <-errCh; err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if !reflect.DeepEqual(results, []int{1, 3, 5, 7, 9}) {
+			t.Fatalf("buffer %d: unexpected results: %v", buffer, results)
+		}
+	}
+
+	// 某一步出错时整个流水线停止并返回错误
+	errBoom := errors.New("boom")
+	in := make(chan []interface{}, 3)
+	in <- []interface{}{1}
+	in <- []interface{}{2}
+	in <- []interface{}{3}
+	out := make(chan []interface{}, 3)
+	err := New(func(n int) (int, error) {
+		if n == 2 {
+			return 0, errBoom
+		}
+		return n, nil
+	}).WithStreamBuffer(2).Stream(context.Background(), in, out)
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected step error, got %v", err)
+	}
+	for range out {
+	}
+}
@@ -0,0 +1,76 @@
+package funchain
+
+import "time"
+
+// Clock is the source of time of the chain, it can be replaced to control time in tests.
+type Clock interface {
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// WithClock sets the clock used by time related features such as AbortAfter and Delay.
+func (fc *FunChain) WithClock(clock Clock) *FunChain {
+	return fc.Configure(WithClock(clock))
+}
+
+// WithClock returns an Option doing the same as FunChain.WithClock.
+func WithClock(clock Clock) Option {
+	return func(fc *FunChain) {
+		fc.clock = clock
+	}
+}
+
+// now returns the current time of the chain's clock.
+func (fc *FunChain) now() time.Time {
+	return fc.getClock().Now()
+}
+
+// getClock returns the clock of the chain.
+func (fc *FunChain) getClock() Clock {
+	if fc.clock == nil {
+		return systemClock{}
+	}
+	return fc.clock
+}
+
+// AbortAfter sets a soft time budget for the chain.
+// Before each function the elapsed time since the start of the run is checked and once it exceeds d,
+// the remaining functions are skipped and the results of the last executed function are returned without error.
+// Unlike a context deadline it never interrupts a running function and isn't reported as a failure,
+// it suits best-effort pipelines where partial results are acceptable.
+func (fc *FunChain) AbortAfter(d time.Duration) *FunChain {
+	return fc.Configure(AbortAfter(d))
+}
+
+// AbortAfter returns an Option doing the same as FunChain.AbortAfter.
+func AbortAfter(d time.Duration) Option {
+	return func(fc *FunChain) {
+		fc.abortAfter = d
+	}
+}
+
+// Delay adds a step that waits for d using the chain's clock and then passes the current arguments on unchanged,
+// e.g. to pace a pipeline. The wait ends early with the context error once the context of the run is done.
+func (fc *FunChain) Delay(d time.Duration) *FunChain {
+	fc.addStep(&step{kind: "Delay", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		select {
+		case <-fc.getClock().After(d):
+			return args, nil
+		case <-rs.ctx.Done():
+			return nil, rs.ctx.Err()
+		}
+	}})
+	return fc
+}
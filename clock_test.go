@@ -0,0 +1,86 @@
+package funchain
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when advanced manually.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// After advances the clock by d and fires immediately.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Advance(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestAbortAfter(t *testing.T) {
+	var (
+		result   int
+		executed int
+		deferred bool
+	)
+	clock := &fakeClock{now: time.Now()}
+	step := func(n int) int {
+		executed++
+		clock.Advance(time.Second)
+		return n + 1
+	}
+	_, err := New(step, step, step, step).Defer(func() {
+		deferred = true
+	}).WithClock(clock).AbortAfter(1500 * time.Millisecond).Do(&result)
+	if err != nil {
+		t.Fatal("aborted chain should not return an error:", err)
+	}
+	if executed != 2 || result != 2 {
+		t.Fatalf("unexpected state: executed=%d, result=%d", executed, result)
+	}
+	if !deferred {
+		t.Fatal("defer functions should run on abort")
+	}
+}
+
+func TestDelay(t *testing.T) {
+	var (
+		result int
+		at     time.Time
+	)
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	_, err := New(func() int {
+		return 1
+	}).Delay(time.Hour).Then(func(n int) int {
+		at = clock.Now()
+		return n + 1
+	}).WithClock(clock).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 2 {
+		t.Fatalf("Delay should pass the arguments through, got %d", result)
+	}
+	if at.Sub(start) != time.Hour {
+		t.Fatalf("next step should run after the delay, ran after %s", at.Sub(start))
+	}
+
+	// 使用真实时钟
+	start = time.Now()
+	if _, err = New().Delay(20 * time.Millisecond).Do(); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Delay returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+// funchainPath is the import path of the funchain package.
+const funchainPath = "github.com/jiazhoulvke/funchain"
+
+// Analyzer checks the steps passed to funchain.New and the Then methods.
+var Analyzer = &analysis.Analyzer{
+	Name:     "funchainvet",
+	Doc:      "report funchain steps that are not functions, have several error results or can't be chained",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// stepFuncs are the functions and methods taking steps as variadic arguments.
+var stepFuncs = map[string]bool{
+	"New":       true,
+	"NewPooled": true,
+	"Then":      true,
+}
+
+var errorType = types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		if !isStepCall(pass, call) {
+			return
+		}
+		var prev types.Type
+		if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+			// New(a).Then(b) 中 a 与 b 相邻
+			if recv, ok := ast.Unparen(sel.X).(*ast.CallExpr); ok && isStepCall(pass, recv) && len(recv.Args) > 0 {
+				prev = pass.TypesInfo.TypeOf(recv.Args[len(recv.Args)-1])
+			}
+		}
+		for _, arg := range call.Args {
+			t := pass.TypesInfo.TypeOf(arg)
+			if t == nil {
+				prev = nil
+				continue
+			}
+			if !maybeStep(t) {
+				pass.Reportf(arg.Pos(), "funchain step must be a function, got %s", t)
+				prev = nil
+				continue
+			}
+			if sig, ok := t.Underlying().(*types.Signature); ok && errorResults(sig) > 1 {
+				pass.Reportf(arg.Pos(), "funchain step %s has more than one error result", t)
+			}
+			if prev != nil {
+				checkAdjacent(pass, arg, prev, t)
+			}
+			prev = t
+		}
+	})
+	return nil, nil
+}
+
+// isStepCall reports whether call is a call to funchain.New or one of the methods adding steps.
+func isStepCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	var ident *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return false
+	}
+	fn, ok := pass.TypesInfo.Uses[ident].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == funchainPath && stepFuncs[fn.Name()]
+}
+
+// maybeStep reports whether a value of type t may be a valid step: a function, a nested chain,
+// a funchain.Step, or an interface whose dynamic value is unknown.
+func maybeStep(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Signature, *types.Interface:
+		return true
+	}
+	if ptr, ok := t.Underlying().(*types.Pointer); ok {
+		if named, ok := ptr.Elem().(*types.Named); ok {
+			obj := named.Obj()
+			if obj.Pkg() != nil && obj.Pkg().Path() == funchainPath && obj.Name() == "FunChain" {
+				return true
+			}
+		}
+	}
+	// 实现了 Step 接口的对象
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "Exec")
+	_, isMethod := obj.(*types.Func)
+	return isMethod
+}
+
+// errorResults returns the number of results of sig implementing error.
+func errorResults(sig *types.Signature) int {
+	n := 0
+	for i := 0; i < sig.Results().Len(); i++ {
+		if types.Implements(sig.Results().At(i).Type(), errorType) {
+			n++
+		}
+	}
+	return n
+}
+
+// checkAdjacent reports the parameters of next that can't receive the results of prev at the same position.
+// Missing arguments are filled with zero values at run time, so only the positions both sides have are checked.
+// A leading context.Context parameter of next receives the context of the run, not a result of prev.
+func checkAdjacent(pass *analysis.Pass, arg ast.Expr, prev, next types.Type) {
+	prevSig, ok1 := prev.Underlying().(*types.Signature)
+	nextSig, ok2 := next.Underlying().(*types.Signature)
+	if !ok1 || !ok2 {
+		return
+	}
+	var results []types.Type
+	for i := 0; i < prevSig.Results().Len(); i++ {
+		if t := prevSig.Results().At(i).Type(); !types.Implements(t, errorType) {
+			results = append(results, t)
+		}
+	}
+	params := nextSig.Params()
+	first := 0
+	if params.Len() > 0 && isContext(params.At(0).Type()) {
+		// 第一个参数由运行时注入的 context 占用
+		first = 1
+	}
+	for i := 0; i < len(results) && first+i < params.Len(); i++ {
+		param := first + i
+		if nextSig.Variadic() && param == params.Len()-1 {
+			return
+		}
+		if _, isInterface := results[i].Underlying().(*types.Interface); isInterface {
+			// 接口的动态类型在运行时才能确定
+			continue
+		}
+		if !mayAssign(results[i], params.At(param).Type()) {
+			pass.Reportf(arg.Pos(), "funchain step argument %d: result of type %s can't be passed to parameter of type %s",
+				i, results[i], params.At(param).Type())
+		}
+	}
+}
+
+// mayAssign reports whether a result of type t may be passed as a parameter of type param: the runtime
+// converts convertible values, except integers to strings.
+func mayAssign(t, param types.Type) bool {
+	if types.AssignableTo(t, param) {
+		return true
+	}
+	return types.ConvertibleTo(t, param) && !(isBasic(t, types.IsInteger) && isBasic(param, types.IsString))
+}
+
+// isBasic reports whether the underlying type of t is a basic type with info.
+func isBasic(t types.Type, info types.BasicInfo) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&info != 0
+}
+
+// isContext reports whether t is context.Context.
+func isContext(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
@@ -0,0 +1,16 @@
+// Command funchainvet reports obvious misuses of funchain that would otherwise only fail at run time:
+// values that are not functions passed as steps, functions with more than one error result, and
+// adjacent functions whose types can't line up.
+//
+// It can be run on its own or through go vet:
+//
+//	go vet -vettool=$(which funchainvet) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(Analyzer)
+}
@@ -0,0 +1,48 @@
+package a
+
+import (
+	"context"
+
+	"github.com/jiazhoulvke/funchain"
+)
+
+type step struct{}
+
+func (step) Name() string { return "step" }
+
+func (step) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) { return args, nil }
+
+func produce() (int, string) { return 1, "a" }
+
+func consume(n int, s string) {}
+
+func wrong(s string) {}
+
+func twoErrors() (error, error) { return nil, nil }
+
+func sum(xs ...int) int { return 0 }
+
+func widen(n int64) int64 { return n }
+
+func itoa(s string) {}
+
+func withContext(ctx context.Context, n int, s string) {}
+
+func wrongWithContext(ctx context.Context, s string) {}
+
+func examples(fn interface{}) {
+	funchain.New(produce, consume)
+	funchain.New(42)                            // want `funchain step must be a function, got int`
+	funchain.New(produce).Then("consume")       // want `funchain step must be a function, got string`
+	funchain.New(produce, wrong)                // want `result of type int can't be passed to parameter of type string`
+	funchain.New(produce).Then(wrong)           // want `result of type int can't be passed to parameter of type string`
+	funchain.New(twoErrors)                     // want `has more than one error result`
+	funchain.New(produce, step{}, fn, consume)  // 接口和 Step 不检查
+	funchain.New(func() int { return 1 }, sum)  // 可变参数不检查
+	funchain.New(funchain.New(produce), wrong)  // 嵌套链不检查
+	funchain.New(func() {}, consume)            // 缺少的参数运行时补零值
+	funchain.New(produce, withContext)          // context 参数由运行时注入
+	funchain.New(produce, widen)                // 可转换的类型运行时自动转换
+	funchain.New(func() int { return 1 }, itoa) // want `result of type int can't be passed to parameter of type string`
+	funchain.New(produce, wrongWithContext)     // want `result of type int can't be passed to parameter of type string`
+}
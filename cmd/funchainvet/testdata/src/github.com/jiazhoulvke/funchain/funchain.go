@@ -0,0 +1,17 @@
+// Package funchain is a stub of the funchain API for the analyzer tests.
+package funchain
+
+import "context"
+
+type FunChain struct{}
+
+type Step interface {
+	Name() string
+	Exec(ctx context.Context, args []interface{}) ([]interface{}, error)
+}
+
+func New(fns ...interface{}) *FunChain { return &FunChain{} }
+
+func (fc *FunChain) Then(fns ...interface{}) *FunChain { return fc }
+
+func (fc *FunChain) Do(out ...interface{}) ([]interface{}, error) { return nil, nil }
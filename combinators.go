@@ -0,0 +1,569 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrFanOutLimit is returned when FlatMap expands into more items than allowed by WithFanOutLimit.
+var ErrFanOutLimit = errors.New("fan-out limit exceeded")
+
+// FlatMap adds a step that expands each element of the slice received as first argument.
+// fn: func(T) []R or func(T) ([]R, error), called once per element.
+// The slices returned by fn are concatenated into a single []R which is passed to the next function.
+func (fc *FunChain) FlatMap(fn interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fc.addStep(&step{fn: fn, kind: "FlatMap", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		return fc.flatMap(fn, args)
+	}})
+	return fc
+}
+
+// WithFanOutLimit limits the total number of items FlatMap steps may expand into.
+// The limit is checked after each element, so expansion stops as soon as it is exceeded.
+// max: maximum number of items, 0 means unlimited.
+func (fc *FunChain) WithFanOutLimit(max int) *FunChain {
+	return fc.Configure(WithFanOutLimit(max))
+}
+
+// WithFanOutLimit returns an Option doing the same as FunChain.WithFanOutLimit.
+func WithFanOutLimit(max int) Option {
+	return func(fc *FunChain) {
+		fc.fanOutLimit = max
+	}
+}
+
+func (fc *FunChain) flatMap(fn interface{}, args []interface{}) ([]interface{}, error) {
+	items, err := sliceArg(args)
+	if err != nil {
+		return nil, err
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.NumOut() == 0 || fnType.Out(0).Kind() != reflect.Slice {
+		return nil, fmt.Errorf("FlatMap function must return a slice, got %s", fnType)
+	}
+	result := reflect.MakeSlice(fnType.Out(0), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		out, err := execFunc(fn, []interface{}{items.Index(i).Interface()}, fc.zeroProvider)
+		if err != nil {
+			return nil, err
+		}
+		expanded := reflect.ValueOf(out[0])
+		if !expanded.IsValid() {
+			continue
+		}
+		if fc.fanOutLimit > 0 && result.Len()+expanded.Len() > fc.fanOutLimit {
+			return nil, fmt.Errorf("%w: element %d expands to %d items (limit %d)",
+				ErrFanOutLimit, i, result.Len()+expanded.Len(), fc.fanOutLimit)
+		}
+		result = reflect.AppendSlice(result, expanded)
+	}
+	return []interface{}{result.Interface()}, nil
+}
+
+// Race adds a step that runs fns concurrently with the current arguments.
+// fns: functions or sub-chains, the results (or the error) of the first one to complete are passed on.
+// Branches completing within a millisecond of the first one, counted from when all branches have started,
+// are considered tied and the one with the lowest index wins, which keeps the outcome of instantly
+// returning branches reproducible. A branch finishing first therefore waits up to a millisecond for the
+// branches before it, unless it's the first one.
+// Branches further apart still race: whichever truly finishes first wins.
+// The losing branches keep running in the background and their results are discarded.
+func (fc *FunChain) Race(fns ...interface{}) *FunChain {
+	branches := stepsOf(fns)
+	if len(branches) == 0 {
+		return fc
+	}
+	fc.addStep(&step{kind: "Race", branches: branches, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		return fc.race(rs, branches, args)
+	}})
+	return fc
+}
+
+// TapStop adds a step that inspects the current arguments without changing them and may end the chain.
+// If fn returns true the remaining functions are skipped and Do returns the current arguments without error,
+// the defer functions still run. Otherwise the arguments are passed unchanged to the next function.
+// The stop only applies to the chain TapStop was added to, a parent chain continues with the results of a nested chain.
+func (fc *FunChain) TapStop(fn func(args []interface{}) bool) *FunChain {
+	if fn == nil {
+		return fc
+	}
+	fc.addStep(&step{fn: fn, kind: "TapStop", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		out, err := execFunc(fn, []interface{}{append([]interface{}(nil), args...)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if out[0].(bool) {
+			return args, ErrStopChain
+		}
+		return args, nil
+	}})
+	return fc
+}
+
+// Tap adds a step that calls fn with the current arguments for a side effect, such as logging or counting,
+// ignores its return values and passes the arguments unchanged to the next function.
+// fn receives the arguments like any function of the chain. If it returns an error or panics, the chain
+// fails as with any other function.
+func (fc *FunChain) Tap(fn interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fc.addStep(&step{fn: fn, kind: "Tap", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		if _, err := execFunc(fn, injectContext(rs.ctx, fn, args), fc.zeroProvider); err != nil {
+			return nil, err
+		}
+		return args, nil
+	}})
+	return fc
+}
+
+// ThenSingleflight adds a function whose concurrent executions are coalesced: while a call for a key is
+// in flight, runs of the chain reaching this step with the same key wait for it and share its results
+// instead of calling fn again, which prevents a stampede on an expensive step.
+// key: derives the key from the current arguments.
+// fn must be safe to share results from, i.e. pure or returning immutable data, since all coalesced
+// runs receive the same values.
+func (fc *FunChain) ThenSingleflight(key func(args []interface{}) string, fn interface{}) *FunChain {
+	if key == nil || !isFunc(fn) {
+		return fc
+	}
+	group := new(singleflight.Group)
+	fc.addStep(&step{fn: fn, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		v, err, _ := group.Do(key(append([]interface{}(nil), args...)), func() (interface{}, error) {
+			return execFunc(fn, args, fc.zeroProvider)
+		})
+		result, _ := v.([]interface{})
+		return append([]interface{}(nil), result...), err
+	}})
+	return fc
+}
+
+// branchResult is the outcome of a branch run concurrently by Race.
+type branchResult struct {
+	index  int
+	result []interface{}
+	err    error
+}
+
+func (fc *FunChain) race(rs *runState, branches []*step, args []interface{}) ([]interface{}, error) {
+	// 缓冲区足够大，落败的分支不会阻塞
+	done := make(chan branchResult, len(branches))
+	var started sync.WaitGroup
+	started.Add(len(branches))
+	for i, st := range branches {
+		go func(i int, st *step, rs *runState) {
+			started.Done()
+			result, err := fc.execStep(rs, st, args)
+			done <- branchResult{index: i, result: result, err: err}
+		}(i, st, rs.fork())
+	}
+	first := <-done
+	if first.index == 0 {
+		return first.result, first.err
+	}
+	// 等所有分支都开始执行后再计时，避免尚未被调度的分支错过平局
+	started.Wait()
+	window := time.NewTimer(raceTieWindow)
+	defer window.Stop()
+	winner := resolveTie(first, done, window.C)
+	return winner.result, winner.err
+}
+
+// raceTieWindow is how long after the first completed branch of Race, and after all branches have started,
+// the branches before it may still complete and win the tie.
+const raceTieWindow = time.Millisecond
+
+// resolveTie compares the first completed branch with the ones completing before window fires and
+// returns the one with the lowest index. It returns as soon as no branch with a lower index is left.
+func resolveTie(first branchResult, done <-chan branchResult, window <-chan time.Time) branchResult {
+	winner := first
+	for winner.index > 0 {
+		select {
+		case r := <-done:
+			if r.index < winner.index {
+				winner = r
+			}
+		case <-window:
+			// 计时结束时已完成的分支仍参与比较
+			for {
+				select {
+				case r := <-done:
+					if r.index < winner.index {
+						winner = r
+					}
+				default:
+					return winner
+				}
+			}
+		}
+	}
+	return winner
+}
+
+// Fork adds a step that runs fns concurrently with the current arguments and waits for all of them.
+// fns: functions or sub-chains.
+// The outputs of all branches are concatenated in the order of fns and passed to the next function.
+// If any branch fails, the chain fails with the error of the failed branch with the lowest index.
+func (fc *FunChain) Fork(fns ...interface{}) *FunChain {
+	branches := stepsOf(fns)
+	if len(branches) == 0 {
+		return fc
+	}
+	fc.addStep(&step{kind: "Fork", branches: branches, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		var outputs []interface{}
+		for _, r := range fc.fork(rs, branches, args) {
+			if r.err != nil {
+				return nil, r.err
+			}
+			outputs = append(outputs, r.result...)
+		}
+		return outputs, nil
+	}})
+	return fc
+}
+
+// ForkPartial is like Fork, but the failure of some branches doesn't fail the chain.
+// The outputs of the successful branches are concatenated in the order of fns, skipping the failed ones,
+// and the chain goes on with them; Do then returns the final results together with the errors of the failed
+// branches joined into one error (see errors.Join), so "best available data" can be aggregated.
+// Since a failed branch contributes no values, the following arguments shift and missing trailing ones
+// are zero-filled: downstream functions should either take the outputs of the branches that may fail last,
+// or have every branch return a single value of the same type, e.g. a slice, and combine them.
+// The chain fails as usual if every branch fails.
+func (fc *FunChain) ForkPartial(fns ...interface{}) *FunChain {
+	branches := stepsOf(fns)
+	if len(branches) == 0 {
+		return fc
+	}
+	fc.addStep(&step{kind: "ForkPartial", branches: branches, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		var (
+			outputs []interface{}
+			errs    []error
+		)
+		results := fc.fork(rs, branches, args)
+		for _, r := range results {
+			if r.err != nil {
+				errs = append(errs, r.err)
+				continue
+			}
+			outputs = append(outputs, r.result...)
+		}
+		if len(errs) == len(results) {
+			return nil, errors.Join(errs...)
+		}
+		return outputs, partialResult(errs)
+	}})
+	return fc
+}
+
+// Parallel adds a step that runs fns concurrently with the current arguments and waits for all of them,
+// like Fork. The outputs are concatenated in the order of fns and passed to the next function.
+// If any function fails, the chain fails with the error of the failed function with the lowest index,
+// once the functions still running returned.
+func (fc *FunChain) Parallel(fns ...interface{}) *FunChain {
+	return fc.ParallelN(0, fns...)
+}
+
+// ParallelN is like Parallel but runs at most n functions at the same time, 0 means no limit.
+// Once a function failed, the functions that didn't start yet are not run.
+func (fc *FunChain) ParallelN(n int, fns ...interface{}) *FunChain {
+	branches := stepsOf(fns)
+	if len(branches) == 0 {
+		return fc
+	}
+	fc.addStep(&step{kind: "Parallel", branches: branches, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		var outputs []interface{}
+		for _, r := range fc.forkN(rs, n, branches, args) {
+			if r.err != nil {
+				return nil, r.err
+			}
+			outputs = append(outputs, r.result...)
+		}
+		return outputs, nil
+	}})
+	return fc
+}
+
+// ParallelContext is like Parallel, but the functions share a context derived from the context of the run
+// (see DoContext) that is cancelled as soon as one of them fails, so that the others can stop early.
+// Functions whose first parameter is a context.Context receive the derived context, and nested chains run
+// with it. The chain fails with the first error that occurred, once all functions returned.
+func (fc *FunChain) ParallelContext(fns ...interface{}) *FunChain {
+	branches := stepsOf(fns)
+	if len(branches) == 0 {
+		return fc
+	}
+	fc.addStep(&step{kind: "ParallelContext", branches: branches, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		g, ctx := errgroup.WithContext(rs.ctx)
+		results := make([][]interface{}, len(branches))
+		for i, st := range branches {
+			branch := rs.fork()
+			branch.ctx = ctx
+			i, st := i, st
+			g.Go(func() error {
+				result, err := fc.execStep(branch, st, args)
+				results[i] = result
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+		var outputs []interface{}
+		for _, result := range results {
+			outputs = append(outputs, result...)
+		}
+		return outputs, nil
+	}})
+	return fc
+}
+
+// fork runs the branches concurrently with args and returns their results in order.
+func (fc *FunChain) fork(rs *runState, branches []*step, args []interface{}) []branchResult {
+	return fc.forkN(rs, 0, branches, args)
+}
+
+// forkN is like fork but runs at most n branches at the same time if n > 0, and stops starting branches
+// once one failed. The results of the branches that didn't start are left empty.
+func (fc *FunChain) forkN(rs *runState, n int, branches []*step, args []interface{}) []branchResult {
+	results := make([]branchResult, len(branches))
+	var (
+		wg     sync.WaitGroup
+		sem    chan struct{}
+		mu     sync.Mutex
+		failed bool
+	)
+	if n > 0 {
+		sem = make(chan struct{}, n)
+	}
+	for i, st := range branches {
+		if sem != nil {
+			sem <- struct{}{}
+			mu.Lock()
+			stop := failed
+			mu.Unlock()
+			if stop {
+				<-sem
+				break
+			}
+		}
+		wg.Add(1)
+		go func(i int, st *step, rs *runState) {
+			defer wg.Done()
+			result, err := fc.execStep(rs, st, args)
+			results[i] = branchResult{index: i, result: result, err: err}
+			if err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+			if sem != nil {
+				<-sem
+			}
+		}(i, st, rs.fork())
+	}
+	wg.Wait()
+	return results
+}
+
+// stepsOf returns the steps of the functions and sub-chains in fns, skipping other values.
+func stepsOf(fns []interface{}) []*step {
+	steps := make([]*step, 0, len(fns))
+	for _, fn := range fns {
+		if isStep(fn) {
+			steps = append(steps, &step{fn: fn})
+		}
+	}
+	return steps
+}
+
+// partialError reports the failures that didn't stop the chain, such as failed ForkPartial branches.
+type partialError struct {
+	errs []error
+}
+
+func (e *partialError) Error() string {
+	return errors.Join(e.errs...).Error()
+}
+
+func (e *partialError) Unwrap() []error {
+	return e.errs
+}
+
+// partialResult returns the partial failures as an error, nil if there are none.
+func partialResult(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &partialError{errs: errs}
+}
+
+// Distinct adds a step that removes the duplicate elements of the slice received as first argument,
+// keeping the first occurrence of each element in its original order. The resulting slice, of the same type,
+// is passed to the next function. Elements of comparable non-interface types are deduplicated with a hash set,
+// other elements, such as slices and maps or structs holding them in interface fields, are compared with
+// reflect.DeepEqual in quadratic time.
+func (fc *FunChain) Distinct() *FunChain {
+	fc.addStep(&step{kind: "Distinct", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		if elem := items.Type().Elem(); !elem.Comparable() || elem.Kind() == reflect.Interface || !allComparable(items) {
+			// 接口类型的动态值可能不可比较，无法作为 map 的键
+			return distinct(items, reflect.DeepEqual, false), nil
+		}
+		seen := make(map[interface{}]bool, items.Len())
+		result := reflect.MakeSlice(reflect.SliceOf(items.Type().Elem()), 0, items.Len())
+		for i := 0; i < items.Len(); i++ {
+			item := items.Index(i)
+			if key := item.Interface(); !seen[key] {
+				seen[key] = true
+				result = reflect.Append(result, item)
+			}
+		}
+		return []interface{}{result.Interface()}, nil
+	}})
+	return fc
+}
+
+// allComparable reports whether every element of items can be used as a map key, which a comparable type
+// doesn't guarantee for structs or arrays holding interfaces.
+func allComparable(items reflect.Value) bool {
+	for i := 0; i < items.Len(); i++ {
+		if !items.Index(i).Comparable() {
+			return false
+		}
+	}
+	return true
+}
+
+// DistinctConsecutive adds a step that collapses runs of equal consecutive elements of the slice received as
+// first argument into their first element, like the Unix uniq command. Elements are compared with reflect.DeepEqual.
+func (fc *FunChain) DistinctConsecutive() *FunChain {
+	fc.addStep(&step{kind: "DistinctConsecutive", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return distinct(items, reflect.DeepEqual, true), nil
+	}})
+	return fc
+}
+
+// DistinctFunc is like Distinct but compares elements with equal, in quadratic time.
+func (fc *FunChain) DistinctFunc(equal func(a, b interface{}) bool) *FunChain {
+	if equal == nil {
+		return fc
+	}
+	fc.addStep(&step{fn: equal, kind: "DistinctFunc", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		return distinct(items, equal, false), nil
+	}})
+	return fc
+}
+
+// distinct removes the elements of items equal to a kept element, or only to the previous one if consecutive.
+func distinct(items reflect.Value, equal func(a, b interface{}) bool, consecutive bool) []interface{} {
+	result := reflect.MakeSlice(reflect.SliceOf(items.Type().Elem()), 0, items.Len())
+	kept := make([]interface{}, 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i).Interface()
+		duplicate := false
+		if consecutive {
+			duplicate = len(kept) > 0 && equal(kept[len(kept)-1], item)
+		} else {
+			for _, k := range kept {
+				if equal(k, item) {
+					duplicate = true
+					break
+				}
+			}
+		}
+		if !duplicate {
+			kept = append(kept, item)
+			result = reflect.Append(result, items.Index(i))
+		}
+	}
+	return []interface{}{result.Interface()}
+}
+
+// Zip adds a step that combines the two slices received as first and second arguments element-wise.
+// fn: func(A, B) R or func(A, B) (R, error), called with the elements at the same index of both slices.
+// The results are collected into a []R passed to the next function. Zip stops at the end of the shorter
+// slice, use ZipStrict to fail on slices of different lengths instead.
+func (fc *FunChain) Zip(fn interface{}) *FunChain {
+	return fc.zip(fn, false)
+}
+
+// ZipStrict is like Zip but fails if the two slices have different lengths.
+func (fc *FunChain) ZipStrict(fn interface{}) *FunChain {
+	return fc.zip(fn, true)
+}
+
+func (fc *FunChain) zip(fn interface{}, strict bool) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fc.addStep(&step{fn: fn, kind: "Zip", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		if len(args) < 2 {
+			return nil, fmt.Errorf("Zip expects two slice arguments, got %d arguments", len(args))
+		}
+		a, err := sliceArg(args[0:1])
+		if err != nil {
+			return nil, err
+		}
+		b, err := sliceArg(args[1:2])
+		if err != nil {
+			return nil, err
+		}
+		fnType := reflect.TypeOf(fn)
+		if fnType.NumIn() != 2 || fnType.NumOut() == 0 || fnType.Out(0).Implements(errorType) {
+			return nil, fmt.Errorf("Zip function must take two arguments and return a value, got %s", fnType)
+		}
+		if !a.Type().Elem().AssignableTo(fnType.In(0)) || !b.Type().Elem().AssignableTo(fnType.In(1)) {
+			return nil, fmt.Errorf("Zip function %s cannot take elements of %s and %s", fnType, a.Type(), b.Type())
+		}
+		if strict && a.Len() != b.Len() {
+			return nil, fmt.Errorf("Zip slices have different lengths: %d and %d", a.Len(), b.Len())
+		}
+		n := a.Len()
+		if b.Len() < n {
+			n = b.Len()
+		}
+		result := reflect.MakeSlice(reflect.SliceOf(fnType.Out(0)), 0, n)
+		for i := 0; i < n; i++ {
+			out, err := execFunc(fn, []interface{}{a.Index(i).Interface(), b.Index(i).Interface()}, fc.zeroProvider)
+			if err != nil {
+				return nil, err
+			}
+			result = reflect.Append(result, elemValue(out[0], fnType.Out(0)))
+		}
+		return []interface{}{result.Interface()}, nil
+	}})
+	return fc
+}
+
+// sliceArg returns the first argument as a slice.
+func sliceArg(args []interface{}) (reflect.Value, error) {
+	if len(args) == 0 {
+		return reflect.Value{}, errors.New("expected a slice argument, got none")
+	}
+	v := reflect.ValueOf(args[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("expected a slice argument, got %T", args[0])
+	}
+	return v, nil
+}
@@ -0,0 +1,502 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlatMap(t *testing.T) {
+	var words []string
+	_, err := New(func() []string {
+		return []string{"a b", "c", "d e f"}
+	}).FlatMap(func(s string) []string {
+		return strings.Fields(s)
+	}).Do(&words)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(words, []string{"a", "b", "c", "d", "e", "f"}) {
+		t.Fatalf("unexpected result: %v", words)
+	}
+
+	// 超出展开上限时报错并指出触发的元素
+	expanded := 0
+	_, err = New(func() []int {
+		return []int{1, 2, 3, 4}
+	}).FlatMap(func(n int) []int {
+		expanded++
+		return make([]int, n)
+	}).WithFanOutLimit(5).Do()
+	if !errors.Is(err, ErrFanOutLimit) {
+		t.Fatalf("expected ErrFanOutLimit, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "element 2") {
+		t.Fatalf("error should report the element index: %v", err)
+	}
+	if expanded != 3 {
+		t.Fatalf("expansion should stop at the offending element, expanded %d elements", expanded)
+	}
+}
+
+func TestRace(t *testing.T) {
+	var result string
+	_, err := New(func() int {
+		return 1
+	}).Race(func(n int) string {
+		time.Sleep(100 * time.Millisecond)
+		return "slow"
+	}, func(n int) string {
+		return "fast"
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "fast" {
+		t.Fatalf("unexpected winner: %s", result)
+	}
+
+	// 同时完成时下标最小的分支获胜
+	done := make(chan branchResult, 3)
+	done <- branchResult{index: 0, result: []interface{}{"first"}}
+	done <- branchResult{index: 1, result: []interface{}{"second"}}
+	winner := resolveTie(branchResult{index: 2, result: []interface{}{"third"}}, done, nil)
+	if winner.index != 0 || winner.result[0] != "first" {
+		t.Fatalf("tie should be won by the lowest index, got %d", winner.index)
+	}
+
+	// 立即返回的分支总是由下标最小的获胜
+	constant := func(s string) func() string {
+		return func() string {
+			return s
+		}
+	}
+	fc := New().Race(constant("a"), constant("b"), constant("c"))
+	for i := 0; i < 200; i++ {
+		if _, err := fc.Do(&result); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if result != "a" {
+			t.Fatalf("run %d: tie won by %q", i, result)
+		}
+	}
+}
+
+func TestTap(t *testing.T) {
+	var (
+		logged []string
+		a      int
+		b      string
+	)
+	_, err := New(func() (int, string) {
+		return 1, "x"
+	}).Tap(func(n int, s string) int {
+		logged = append(logged, strings.Repeat(s, n+1))
+		return 100
+	}).Do(&a, &b)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 返回值被忽略，参数原样传递
+	if a != 1 || b != "x" || len(logged) != 1 || logged[0] != "xx" {
+		t.Fatalf("unexpected state: a=%d, b=%q, logged=%v", a, b, logged)
+	}
+
+	errTap := errors.New("tap failed")
+	var next bool
+	_, err = New(func() int {
+		return 1
+	}).Tap(func(n int) error {
+		return errTap
+	}).Then(func(n int) {
+		next = true
+	}).Do()
+	if !errors.Is(err, errTap) || next {
+		t.Fatalf("unexpected state: err=%v, next=%v", err, next)
+	}
+}
+
+func TestTapStop(t *testing.T) {
+	var (
+		result   int
+		executed bool
+		deferred bool
+	)
+	fc := New(func() int {
+		return 1
+	}).TapStop(func(args []interface{}) bool {
+		return args[0].(int) > 0
+	}).Then(func(n int) int {
+		executed = true
+		return n + 1
+	}).Defer(func() {
+		deferred = true
+	})
+	_, err := fc.Do(&result)
+	if err != nil {
+		t.Fatal("stopped chain should not return an error:", err)
+	}
+	if executed || result != 1 || !deferred {
+		t.Fatalf("unexpected state: executed=%v, result=%d, deferred=%v", executed, result, deferred)
+	}
+
+	// 返回 false 时参数原样传递
+	_, err = New(func() int {
+		return 0
+	}).TapStop(func(args []interface{}) bool {
+		return false
+	}).Then(func(n int) int {
+		return n + 10
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 10 {
+		t.Fatalf("unexpected result: expected 10, got %d", result)
+	}
+}
+
+func TestThenSingleflight(t *testing.T) {
+	var (
+		calls   int32
+		release = make(chan struct{})
+	)
+	fc := New(func() string {
+		return "key"
+	}).ThenSingleflight(func(args []interface{}) string {
+		return args[0].(string)
+	}, func(key string) int {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return len(key)
+	})
+
+	var wg sync.WaitGroup
+	results := make([]int, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := fc.Do(&results[i]); err != nil {
+				t.Error("Chain execution error:", err)
+			}
+		}(i)
+	}
+	// 等待所有执行都进入同一个调用后再放行
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("concurrent runs should be coalesced into one call, got %d calls", calls)
+	}
+	for _, result := range results {
+		if result != 3 {
+			t.Fatalf("unexpected results: %v", results)
+		}
+	}
+}
+
+func TestFork(t *testing.T) {
+	var (
+		sum     int
+		product int
+	)
+	_, err := New(func() (int, int) {
+		return 3, 4
+	}).Fork(func(a, b int) int {
+		return a + b
+	}, func(a, b int) int {
+		return a * b
+	}).Do(&sum, &product)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if sum != 7 || product != 12 {
+		t.Fatalf("unexpected results: sum=%d, product=%d", sum, product)
+	}
+
+	errBranch := errors.New("branch failed")
+	_, err = New(func() int {
+		return 1
+	}).Fork(func(n int) int {
+		return n
+	}, func(n int) (int, error) {
+		return 0, errBranch
+	}).Do()
+	if !errors.Is(err, errBranch) {
+		t.Fatalf("expected branch error, got %v", err)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	// 输出按函数顺序合并，与完成顺序无关
+	var a, b, c int
+	_, err := New(func() int {
+		return 2
+	}).Parallel(func(n int) int {
+		time.Sleep(20 * time.Millisecond)
+		return n
+	}, func(n int) (int, int) {
+		return n * 2, n * 3
+	}).Do(&a, &b, &c)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if a != 2 || b != 4 || c != 6 {
+		t.Fatalf("unexpected results: %d %d %d", a, b, c)
+	}
+
+	var (
+		running, most int32
+		hooked        error
+		started       int32
+	)
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	branch := func(err error) func() error {
+		return func() error {
+			atomic.AddInt32(&started, 1)
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&most)
+				if n <= m || atomic.CompareAndSwapInt32(&most, m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return err
+		}
+	}
+	_, err = New().ParallelN(2, branch(nil), branch(errFirst), branch(errSecond), branch(nil), branch(nil)).OnError(func(args []interface{}, err error) {
+		hooked = err
+	}).Do()
+	if !errors.Is(err, errFirst) || hooked != errFirst {
+		t.Fatalf("expected the first error, got %v (hook: %v)", err, hooked)
+	}
+	if most > 2 {
+		t.Fatalf("expected at most 2 concurrent functions, got %d", most)
+	}
+	// 出错后不再启动剩余的函数
+	if started == 5 {
+		t.Fatal("expected the remaining functions to be skipped after the failure")
+	}
+}
+
+func TestParallelContext(t *testing.T) {
+	errFast := errors.New("fast branch failed")
+	var (
+		cancelled bool
+		hooked    error
+	)
+	start := time.Now()
+	_, err := New(func() int {
+		return 1
+	}).ParallelContext(func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-ctx.Done():
+			cancelled = true
+			return 0, ctx.Err()
+		case <-time.After(5 * time.Second):
+			return n, nil
+		}
+	}, func(n int) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return 0, errFast
+	}).OnError(func(args []interface{}, err error) {
+		hooked = err
+	}).Do()
+	if !errors.Is(err, errFast) || !errors.Is(hooked, errFast) {
+		t.Fatalf("expected the first error, got %v (hook: %v)", err, hooked)
+	}
+	// 慢的分支在快的分支失败后被取消
+	if !cancelled || time.Since(start) > time.Second {
+		t.Fatalf("slow branch wasn't cancelled: cancelled=%v, elapsed=%v", cancelled, time.Since(start))
+	}
+
+	var a, b int
+	_, err = New(func() int {
+		return 2
+	}).ParallelContext(func(ctx context.Context, n int) int {
+		return n + 1
+	}, func(n int) int {
+		return n * 10
+	}).Do(&a, &b)
+	if err != nil || a != 3 || b != 20 {
+		t.Fatalf("unexpected state: a=%d, b=%d, err=%v", a, b, err)
+	}
+}
+
+func TestForkPartial(t *testing.T) {
+	errA := errors.New("a failed")
+	errC := errors.New("c failed")
+	var total []string
+	result, err := New(func() string {
+		return "x"
+	}).ForkPartial(func(s string) ([]string, error) {
+		return nil, errA
+	}, func(s string) []string {
+		return []string{s + "b"}
+	}, func(s string) ([]string, error) {
+		return nil, errC
+	}, func(s string) []string {
+		return []string{s + "d"}
+	}).Then(func(b, d []string) []string {
+		return append(b, d...)
+	}).Do(&total)
+	if !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Fatalf("expected joined branch errors, got %v", err)
+	}
+	if !reflect.DeepEqual(result, []interface{}{[]string{"xb", "xd"}}) {
+		t.Fatalf("chain should go on with the successful branches, got %v", result)
+	}
+	if !reflect.DeepEqual(total, []string{"xb", "xd"}) {
+		t.Fatalf("results should be bound despite partial failures, got %v", total)
+	}
+
+	// 所有分支都失败时函数链失败
+	executed := false
+	_, err = New(func() int {
+		return 1
+	}).ForkPartial(func(n int) error {
+		return errA
+	}).Then(func() {
+		executed = true
+	}).Do()
+	if !errors.Is(err, errA) || executed {
+		t.Fatalf("chain should fail when every branch fails: err=%v, executed=%v", err, executed)
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	var result []int
+	_, err := New(func() []int {
+		return []int{3, 1, 3, 2, 1, 1}
+	}).Distinct().Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(result, []int{3, 1, 2}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	_, err = New(func() []int {
+		return []int{3, 1, 1, 3, 3, 1}
+	}).DistinctConsecutive().Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(result, []int{3, 1, 3, 1}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	// 不可比较的元素使用 reflect.DeepEqual
+	var pairs [][]int
+	_, err = New(func() [][]int {
+		return [][]int{{1, 2}, {3}, {1, 2}}
+	}).Distinct().Do(&pairs)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(pairs, [][]int{{1, 2}, {3}}) {
+		t.Fatalf("unexpected result: %v", pairs)
+	}
+
+	// 可比较的结构体中的接口字段持有不可比较的值
+	type boxed struct {
+		V interface{}
+	}
+	var boxes []boxed
+	_, err = New(func() []boxed {
+		return []boxed{{[]int{1}}, {[]int{2}}, {[]int{1}}}
+	}).Distinct().Do(&boxes)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(boxes, []boxed{{[]int{1}}, {[]int{2}}}) {
+		t.Fatalf("unexpected result: %v", boxes)
+	}
+
+	var words []string
+	_, err = New(func() []string {
+		return []string{"Go", "go", "Rust", "GO"}
+	}).DistinctFunc(func(a, b interface{}) bool {
+		return strings.EqualFold(a.(string), b.(string))
+	}).Do(&words)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(words, []string{"Go", "Rust"}) {
+		t.Fatalf("unexpected result: %v", words)
+	}
+}
+
+func TestZip(t *testing.T) {
+	var result []string
+	_, err := New(func() ([]string, []int) {
+		return []string{"a", "b", "c"}, []int{1, 2}
+	}).Zip(func(s string, n int) string {
+		return strings.Repeat(s, n)
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(result, []string{"a", "bb"}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	_, err = New(func() ([]string, []int) {
+		return []string{"a", "b", "c"}, []int{1, 2}
+	}).ZipStrict(func(s string, n int) string {
+		return s
+	}).Do()
+	if err == nil || !strings.Contains(err.Error(), "different lengths") {
+		t.Fatalf("expected length mismatch error, got %v", err)
+	}
+
+	// 元素类型与函数参数不匹配
+	_, err = New(func() ([]string, []int) {
+		return []string{"a"}, []int{1}
+	}).Zip(func(a, b string) string {
+		return a + b
+	}).Do()
+	if err == nil || !strings.Contains(err.Error(), "cannot take elements") {
+		t.Fatalf("expected type mismatch error, got %v", err)
+	}
+	// 函数返回 nil 接口值时得到零值
+	var values []interface{}
+	_, err = New(func() ([]string, []int) {
+		return []string{"a", "b"}, []int{1, 2}
+	}).Zip(func(s string, n int) interface{} {
+		if n == 1 {
+			return nil
+		}
+		return s
+	}).Do(&values)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(values, []interface{}{nil, "b"}) {
+		t.Fatalf("unexpected result: %v", values)
+	}
+}
+
+func TestCombinatorPanic(t *testing.T) {
+	fc := New(func() int {
+		return 1
+	})
+	fc.addStep(&step{kind: "Broken", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		panic("broken combinator")
+	}})
+	// 组合步骤内部的 panic 不会逃出 Do
+	_, err := fc.Do()
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value() != "broken combinator" {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+}
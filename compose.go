@@ -0,0 +1,51 @@
+package funchain
+
+// Append adds the steps of other to the end of the chain, so that reusable sub-pipelines can be glued
+// together. Unlike Then(other), which runs other as a nested chain, the steps are flattened into the chain.
+// The hooks and defers of other are added to those of the chain: after Append both chains' Before, After,
+// error, recover and mutate hooks run for every step, the chain's first. Settings of other, such as
+// WithRetry or WithName, are not taken over. Later changes to other don't affect the chain.
+// The steps, hooks and defers are added at once, runs starting meanwhile see either all or none of them.
+// Use Validate to check that the types match across the seam.
+func (fc *FunChain) Append(other *FunChain) *FunChain {
+	if other == nil {
+		return fc
+	}
+	src := other.self().copyConfig()
+	fc.mu.Lock()
+	defer fc.unlock()
+	from := len(fc.steps)
+	fc.steps = append(fc.steps, src.steps...)
+	if fc.onDuplicate != nil {
+		for i := from; i < len(fc.steps); i++ {
+			fc.checkDuplicate(i)
+		}
+	}
+	fc.defers = append(fc.defers, src.defers...)
+	fc.beforeHooks = append(fc.beforeHooks, src.beforeHooks...)
+	fc.afterHooks = append(fc.afterHooks, src.afterHooks...)
+	fc.errHooks = append(fc.errHooks, src.errHooks...)
+	fc.recoverHooks = append(fc.recoverHooks, src.recoverHooks...)
+	fc.mutateHooks = append(fc.mutateHooks, src.mutateHooks...)
+	return fc
+}
+
+// Concat returns a new chain made of the steps of chains in order, see Append for how hooks and defers
+// are merged. The new chain has the settings of the first chain, nil chains are skipped.
+// The chains themselves are not modified. Pipe composes typed chains instead.
+func Concat(chains ...*FunChain) *FunChain {
+	var fc *FunChain
+	for _, c := range chains {
+		switch {
+		case c == nil:
+		case fc == nil:
+			fc = c.Clone()
+		default:
+			fc.Append(c)
+		}
+	}
+	if fc == nil {
+		return New()
+	}
+	return fc
+}
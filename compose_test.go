@@ -0,0 +1,92 @@
+package funchain
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAppend(t *testing.T) {
+	var (
+		steps  int
+		closed []string
+	)
+	openRead := New(func(s string) io.Reader {
+		return strings.NewReader(s)
+	}, io.ReadAll).Defer(func() {
+		closed = append(closed, "reader")
+	}).After(func(input, output []interface{}) {
+		steps++
+	})
+	transform := New(func(data []byte) string {
+		return strings.ToUpper(string(data))
+	}, strings.Fields).Defer(func() {
+		closed = append(closed, "transform")
+	})
+
+	combined := Concat(openRead, transform).Input("hello world")
+	if err := combined.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	var words []string
+	if _, err := combined.Do(&words); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if len(words) != 2 || words[0] != "HELLO" || words[1] != "WORLD" {
+		t.Fatalf("unexpected result: %v", words)
+	}
+	// 两个链的 defer 都执行，钩子作用于所有步骤
+	if steps != 4 || len(closed) != 2 {
+		t.Fatalf("unexpected state: steps=%d, closed=%v", steps, closed)
+	}
+	// 原来的链不受影响
+	if len(openRead.steps) != 2 || len(transform.steps) != 2 {
+		t.Fatalf("source chains were modified: %d, %d", len(openRead.steps), len(transform.steps))
+	}
+
+	// 接缝处类型不匹配时 Validate 报错
+	if err := New(func() int { return 1 }).Append(transform).Validate(); err == nil {
+		t.Fatal("expected type mismatch across the seam")
+	}
+}
+
+func TestAppendAtomic(t *testing.T) {
+	inc := func(n int) int {
+		return n + 1
+	}
+	var hooked int
+	other := New(inc, inc).Before(func(input []interface{}) {
+		hooked++
+	})
+	var duplicates [][2]int
+	fc := New(func() int {
+		return 0
+	}, inc).WarnOnDuplicateSteps(func(first, dup int, name string) {
+		duplicates = append(duplicates, [2]int{first, dup})
+	})
+	done := make(chan struct{})
+	go func() {
+		fc.Append(other)
+		close(done)
+	}()
+	// 并发执行时要么看到追加的全部步骤和钩子，要么都看不到
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+		hooked = 0
+		var result int
+		if _, err := fc.Do(&result); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if (result != 1 || hooked != 0) && (result != 3 || hooked != 4) {
+			t.Fatalf("run saw a partial Append: result=%d, hooked=%d", result, hooked)
+		}
+	}
+	// 追加的步骤也检查重复
+	if len(duplicates) != 2 || duplicates[0] != [2]int{1, 2} || duplicates[1] != [2]int{1, 3} {
+		t.Fatalf("unexpected duplicates: %v", duplicates)
+	}
+}
@@ -0,0 +1,72 @@
+package funchain
+
+import "reflect"
+
+// DuplicateStepFunc is called when a function is added to a chain more than once.
+// first: index of the first occurrence of the function.
+// dup: index of the duplicate.
+// name: name of the function.
+type DuplicateStepFunc func(first, dup int, name string)
+
+// WarnOnDuplicateSteps reports, through fn, functions or nested chains added to the chain more than once,
+// which is usually a copy-paste mistake. The steps already in the chain are checked immediately, the
+// following ones as they are added. It is only a diagnostic, the duplicates are still executed.
+// Functions are compared by their code pointer, so closures created by the same function literal
+// are reported as duplicates too. fn is called once the step is added, so it may use the chain, e.g. Describe it.
+func (fc *FunChain) WarnOnDuplicateSteps(fn DuplicateStepFunc) *FunChain {
+	return fc.Configure(WarnOnDuplicateSteps(fn))
+}
+
+// WarnOnDuplicateSteps returns an Option doing the same as FunChain.WarnOnDuplicateSteps.
+func WarnOnDuplicateSteps(fn DuplicateStepFunc) Option {
+	return func(fc *FunChain) {
+		fc.onDuplicate = fn
+		if fn != nil {
+			for i := range fc.steps {
+				fc.checkDuplicate(i)
+			}
+		}
+	}
+}
+
+// checkDuplicate reports the step at index i if the same function appears before it.
+// fc.mu must be held, the report is queued and made by unlock, so that the callback may use the chain.
+func (fc *FunChain) checkDuplicate(i int) {
+	st := fc.steps[i]
+	if st.fn == nil {
+		return
+	}
+	for j := 0; j < i; j++ {
+		if fc.steps[j].fn != nil && sameStep(fc.steps[j].fn, st.fn) {
+			onDuplicate, resolver := fc.onDuplicate, fc.nameResolver
+			fc.queued = append(fc.queued, func() {
+				onDuplicate(j, i, stepNameWith(resolver, st))
+			})
+			return
+		}
+	}
+}
+
+// unlock releases fc.mu and runs the callbacks queued while it was held.
+func (fc *FunChain) unlock() {
+	queued := fc.queued
+	fc.queued = nil
+	fc.mu.Unlock()
+	for _, f := range queued {
+		f()
+	}
+}
+
+// sameStep reports whether a and b are the same function, nested chain or Step.
+func sameStep(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	switch va.Kind() {
+	case reflect.Func, reflect.Ptr:
+		return va.Pointer() == vb.Pointer()
+	}
+	// Step 的其他实现只有可比较时才能判断
+	return va.Type().Comparable() && a == b
+}
@@ -0,0 +1,55 @@
+package funchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWarnOnDuplicateSteps(t *testing.T) {
+	type report struct {
+		first, dup int
+		name       string
+	}
+	var reports []report
+	sub := New(double)
+	New(double, func(n int) int {
+		return n
+	}, double).WarnOnDuplicateSteps(func(first, dup int, name string) {
+		reports = append(reports, report{first, dup, name})
+	}).Then(sub, sub, double)
+	if len(reports) != 3 {
+		t.Fatalf("expected 3 duplicates, got %v", reports)
+	}
+	if reports[0].first != 0 || reports[0].dup != 2 || !strings.HasSuffix(reports[0].name, ".double") {
+		t.Fatalf("unexpected report: %+v", reports[0])
+	}
+	if reports[1].first != 3 || reports[1].dup != 4 || reports[1].name != "FunChain" {
+		t.Fatalf("unexpected report: %+v", reports[1])
+	}
+	if reports[2].first != 0 || reports[2].dup != 5 {
+		t.Fatalf("unexpected report: %+v", reports[2])
+	}
+}
+
+func TestWarnOnDuplicateStepsReentrant(t *testing.T) {
+	var (
+		fc        *FunChain
+		described []string
+	)
+	fc = New(double).WithNameResolver(func(fn interface{}) string {
+		return "step"
+	}).WarnOnDuplicateSteps(func(first, dup int, name string) {
+		// 回调中可以使用同一个链，不会死锁
+		described = append(described, fc.StepName(dup)+" "+fc.Describe())
+		if dup == 1 {
+			fc.Then(func(n int) int { return n })
+		}
+	})
+	fc.Then(double)
+	if len(described) != 1 || len(fc.steps) != 3 {
+		t.Fatalf("unexpected state: reports=%v, steps=%d", described, len(fc.steps))
+	}
+	if described[0] != "step 1:step -> 2:step" {
+		t.Fatalf("unexpected report: %q", described[0])
+	}
+}
@@ -0,0 +1,63 @@
+package funchain
+
+// InsertAt inserts fns before the step at index, e.g. to add a logging step in the middle of a chain
+// assembled by plugins. index may be the number of steps to append at the end.
+// Values that are not functions, Steps or chains are skipped like in New. An index out of range leaves the
+// chain unchanged.
+func (fc *FunChain) InsertAt(index int, fns ...interface{}) *FunChain {
+	fc.mu.Lock()
+	defer fc.unlock()
+	if index < 0 || index > len(fc.steps) {
+		return fc
+	}
+	inserted := make([]*step, 0, len(fns))
+	for _, fn := range fns {
+		if isStep(fn) {
+			inserted = append(inserted, &step{fn: fn})
+		}
+	}
+	steps := make([]*step, 0, len(fc.steps)+len(inserted))
+	steps = append(steps, fc.steps[:index]...)
+	steps = append(steps, inserted...)
+	fc.steps = append(steps, fc.steps[index:]...)
+	if fc.onDuplicate != nil {
+		for i := index; i < index+len(inserted); i++ {
+			fc.checkDuplicate(i)
+		}
+	}
+	return fc
+}
+
+// ReplaceAt replaces the step at index with fn, dropping the settings of the replaced step such as its name.
+// A value that is not a function, Step or chain, or an index out of range, leaves the chain unchanged.
+func (fc *FunChain) ReplaceAt(index int, fn interface{}) *FunChain {
+	if !isStep(fn) {
+		return fc
+	}
+	fc.mu.Lock()
+	defer fc.unlock()
+	if index < 0 || index >= len(fc.steps) {
+		return fc
+	}
+	// 复制切片，避免影响共享底层数组的快照和克隆
+	steps := append([]*step(nil), fc.steps...)
+	steps[index] = &step{fn: fn}
+	fc.steps = steps
+	if fc.onDuplicate != nil {
+		fc.checkDuplicate(index)
+	}
+	return fc
+}
+
+// RemoveAt removes the step at index. An index out of range leaves the chain unchanged.
+func (fc *FunChain) RemoveAt(index int) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if index < 0 || index >= len(fc.steps) {
+		return fc
+	}
+	steps := make([]*step, 0, len(fc.steps)-1)
+	steps = append(steps, fc.steps[:index]...)
+	fc.steps = append(steps, fc.steps[index+1:]...)
+	return fc
+}
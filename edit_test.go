@@ -0,0 +1,47 @@
+package funchain
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestEditSteps(t *testing.T) {
+	var order []string
+	step := func(name string, delta int) func(int) int {
+		return func(n int) int {
+			order = append(order, name)
+			return n + delta
+		}
+	}
+	fc := New(func() int {
+		return 1
+	}, step("add", 1), step("double", 0))
+	fc.ReplaceAt(2, func(n int) int {
+		order = append(order, "double")
+		return n * 2
+	})
+	var logged []interface{}
+	fc.InsertAt(2, func(n int) int {
+		order = append(order, "log")
+		logged = append(logged, n)
+		return n
+	}, "not a function")
+
+	var result int
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 4 || !reflect.DeepEqual(order, []string{"add", "log", "double"}) || fmt.Sprint(logged) != "[2]" {
+		t.Fatalf("unexpected state: result=%d, order=%v, logged=%v", result, order, logged)
+	}
+
+	order = nil
+	fc.RemoveAt(1).RemoveAt(10).InsertAt(-1, step("ignored", 0)).ReplaceAt(5, step("ignored", 0))
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 2 || !reflect.DeepEqual(order, []string{"log", "double"}) {
+		t.Fatalf("unexpected state: result=%d, order=%v", result, order)
+	}
+}
@@ -0,0 +1,114 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventType is the type of a lifecycle event of a chain run.
+type EventType int
+
+const (
+	// EventStepStart is emitted before a step is executed.
+	EventStepStart EventType = iota
+	// EventStepEnd is emitted after a step is executed, with its duration and error.
+	EventStepEnd
+	// EventChainEnd is emitted when a run of the chain completes, with its duration and error.
+	EventChainEnd
+	// EventDefer is emitted after a defer function ran, with its duration and the panic it raised if any.
+	// The defer functions run after EventChainEnd.
+	EventDefer
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventStepStart:
+		return "step_start"
+	case EventStepEnd:
+		return "step_end"
+	case EventChainEnd:
+		return "chain_end"
+	case EventDefer:
+		return "defer"
+	}
+	return "unknown"
+}
+
+// Event is a lifecycle event of a chain run.
+type Event struct {
+	Type EventType
+	// Chain is the name of the chain set with WithName.
+	Chain string
+	// Step is the index of the step, or of the defer function in order of registration, -1 for chain events.
+	Step int
+	// StepName is the name of the step, empty for chain events.
+	StepName string
+	// Duration is the execution time of the step or the chain, zero for EventStepStart.
+	Duration time.Duration
+	// Err is the error of the step or the chain, if any.
+	Err error
+}
+
+// Panicked reports whether the error of the event was caused by a panic.
+func (e Event) Panicked() bool {
+	var pe *PanicError
+	return errors.As(e.Err, &pe)
+}
+
+// EventSink receives the lifecycle events of chain runs, e.g. to export metrics.
+// Handle is called synchronously from the goroutine executing the chain, so it should return quickly.
+type EventSink interface {
+	Handle(e Event)
+}
+
+// EventSinkFunc adapts a function to an EventSink.
+type EventSinkFunc func(e Event)
+
+// Handle calls f(e).
+func (f EventSinkFunc) Handle(e Event) {
+	f(e)
+}
+
+// WithName sets the name of the chain, reported in events.
+func (fc *FunChain) WithName(name string) *FunChain {
+	return fc.Configure(WithName(name))
+}
+
+// WithName returns an Option doing the same as FunChain.WithName.
+func WithName(name string) Option {
+	return func(fc *FunChain) {
+		fc.name = name
+	}
+}
+
+// WithEventSink adds sinks receiving the lifecycle events of every run of the chain.
+// The events of a nested chain are only sent to the sinks of that chain.
+func (fc *FunChain) WithEventSink(sinks ...EventSink) *FunChain {
+	return fc.Configure(WithEventSink(sinks...))
+}
+
+// WithEventSink returns an Option doing the same as FunChain.WithEventSink.
+func WithEventSink(sinks ...EventSink) Option {
+	return func(fc *FunChain) {
+		for _, sink := range sinks {
+			if sink != nil {
+				fc.sinks = append(fc.sinks, sink)
+			}
+		}
+	}
+}
+
+// emit sends e to all sinks with recovery protection.
+func (fc *FunChain) emit(e Event) {
+	for _, sink := range fc.sinks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Println("Panic from event sink:", r)
+				}
+			}()
+			sink.Handle(e)
+		}()
+	}
+}
@@ -0,0 +1,49 @@
+package funchain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEventSink(t *testing.T) {
+	var events []Event
+	_, err := New(func() int {
+		return 1
+	}, func(n int) error {
+		return errors.New("failed")
+	}).WithName("test").WithEventSink(EventSinkFunc(func(e Event) {
+		events = append(events, e)
+	})).Do()
+	if err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	expected := []struct {
+		typ  EventType
+		step int
+		err  bool
+	}{
+		{EventStepStart, 0, false},
+		{EventStepEnd, 0, false},
+		{EventStepStart, 1, false},
+		{EventStepEnd, 1, true},
+		{EventChainEnd, -1, true},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	for i, e := range expected {
+		if events[i].Type != e.typ || events[i].Step != e.step || (events[i].Err != nil) != e.err || events[i].Chain != "test" {
+			t.Fatalf("unexpected event %d: %+v", i, events[i])
+		}
+	}
+
+	events = nil
+	_, _ = New(func() {
+		panic("boom")
+	}).WithEventSink(EventSinkFunc(func(e Event) {
+		events = append(events, e)
+	})).Do()
+	if !events[1].Panicked() || events[1].Type != EventStepEnd {
+		t.Fatalf("step end event should report the panic: %+v", events[1])
+	}
+}
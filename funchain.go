@@ -1,19 +1,156 @@
 package funchain
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"sync"
+	"time"
 )
 
+// ErrMaxDepthExceeded is returned when nested chains go deeper than the limit set by WithMaxDepth.
+var ErrMaxDepthExceeded = errors.New("maximum chain nesting depth exceeded")
+
+// ErrStopChain can be returned by a function to end the chain successfully, e.g. on a cache hit: the remaining
+// functions are skipped and Do returns the arguments the function received, with a nil error.
+// It is not a failure: the error hooks are not called, the step is not retried and any value returned along
+// with it is ignored. It is recognized with errors.Is, so it can be wrapped; an error wrapping both
+// ErrStopChain and a real failure stops the chain without error too. Only the chain the function belongs
+// to stops, a parent chain continues with the results of a nested chain.
+var ErrStopChain = errors.New("stop chain")
+
+// ErrPanic matches the errors caused by a panic with errors.Is, e.g. to tell them apart from returned
+// errors in error hooks. See PanicError for the details of the panic.
+var ErrPanic = errors.New("panic")
+
+// ErrCyclicChain is returned when a chain is nested into itself, directly or through other chains.
+var ErrCyclicChain = errors.New("cyclic chain nesting")
+
+// errorType is the reflect.Type of the error interface.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// contextType is the reflect.Type of the context.Context interface.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
 // FunChain is the main type that supports chaining multiple functions.
 // It provides methods to add functions to the chain along with hooks and defer (cleanup) functions.
 type FunChain struct {
-	funcs       []interface{}
-	defers      []func()
-	beforeHooks []BeforeHookFunc
-	afterHooks  []AfterHookFunc
-	errHooks    []ErrorHookFunc
+	steps           []*step
+	defers          []func()
+	beforeHooks     []beforeHook
+	afterHooks      []afterHook
+	errHooks        []errorHook
+	recoverHooks    []RecoverHookFunc
+	mutateHooks     []MutatingBeforeHook
+	redactor        RedactFunc
+	fanOutLimit     int
+	zeroProvider    ZeroProvider
+	clock           Clock
+	abortAfter      time.Duration
+	resumePanic     ResumePanicFunc
+	watchdog        *watchdogConfig
+	classifier      func(error) Severity
+	onDuplicate     DuplicateStepFunc
+	maxDepth        int
+	name            string
+	sinks           []EventSink
+	hookTiming      HookTiming
+	input           func() []interface{}
+	cache           Cache
+	cacheTTL        time.Duration
+	cacheKey        func(seed []interface{}) (string, error)
+	binder          Binder
+	argHistory      bool
+	nameResolver    func(fn interface{}) string
+	streamBuffer    int
+	stepTimeout     time.Duration
+	onAbandoned     AbandonedStepFunc
+	retries         int
+	retryBackoff    func(attempt int) time.Duration
+	retryIf         func(err error) bool
+	continueOnError bool
+	strictArity     bool
+	rawErrors       bool
+	logger          Logger
+	tracer          Tracer
+	// released is set by Release, a released chain can't be executed anymore.
+	released bool
+	// pooled is set by NewPooled, only the slices of pooled chains are returned to the pool by Release.
+	pooled bool
+
+	// mu guards the configuration above against concurrent builder calls, see snapshot.
+	mu sync.RWMutex
+	// queued are the callbacks to run once mu is released, see unlock.
+	queued []func()
+	// origin is the chain a snapshot was taken from, nil for chains built by the user.
+	origin *FunChain
+
+	statsMu sync.Mutex
+	stats   Stats
+
+	historyMu sync.Mutex
+	history   [][]interface{}
+}
+
+// step is a function (or nested chain) of the chain together with its per-step settings.
+type step struct {
+	fn interface{}
+	// doc is a developer supplied description of the step.
+	doc string
+	// label is a developer supplied name of the step, see ThenNamed.
+	label string
+	// mapArgs, if set, transforms the incoming arguments before they are passed to fn.
+	mapArgs func(fc *FunChain, args []interface{}) []interface{}
+	// panicAsValue passes a panic downstream as a Panic value instead of failing the chain.
+	panicAsValue bool
+	// exec, if set, executes the step instead of calling fn directly, it is used by the built-in combinators.
+	// It receives the chain being executed, so that it reads the settings of the snapshot of the run.
+	exec func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error)
+	// kind is the name of the combinator that created the step, empty for plain functions.
+	kind string
+	// branches are the steps run by a combinator such as Race.
+	branches []*step
+	// bind is the struct pointer of a Bind step.
+	bind interface{}
+	// always, if set, is a ThenAlways step that also runs after a prior step failed.
+	always func(fc *FunChain, failed error, args []interface{}) ([]interface{}, error)
+	// clearsErr reports whether the ThenAlways function can clear the error, see ThenAlways.
+	clearsErr bool
+	// compensation, if set, undoes the step when a later step fails, see ThenCompensable.
+	compensation interface{}
+	// cond, if set, decides with the current arguments whether the step runs, see When.
+	cond func(args []interface{}) bool
+}
+
+// Panic is passed downstream in place of the return values of a step added by ThenPanicAsValue when it panics.
+type Panic struct {
+	// Value is the value recovered from the panic.
+	Value interface{}
+}
+
+// beforeHook is a registered BeforeHookFunc with an optional condition.
+// A nil cond means the hook is always enabled.
+type beforeHook struct {
+	fn      BeforeHookFunc
+	indexed BeforeIndexedHookFunc
+	cond    func() bool
+}
+
+// afterHook is a registered AfterHookFunc or TimedAfterHookFunc with an optional condition.
+type afterHook struct {
+	fn      AfterHookFunc
+	timed   TimedAfterHookFunc
+	indexed AfterIndexedHookFunc
+	cond    func() bool
+}
+
+// errorHook is a registered ErrorHookFunc with an optional condition.
+type errorHook struct {
+	fn      ErrorHookFunc
+	indexed ErrorIndexedHookFunc
+	cond    func() bool
 }
 
 // ErrorHookFunc is an error handling hook function.
@@ -30,45 +167,343 @@ type BeforeHookFunc func(input []interface{})
 // output: function return values
 type AfterHookFunc func(input []interface{}, output []interface{})
 
+// TimedAfterHookFunc is an AfterHookFunc that also receives how long the function took.
+// d: execution time of the function, measured with the chain's clock
+type TimedAfterHookFunc func(input []interface{}, output []interface{}, d time.Duration)
+
+// BeforeIndexedHookFunc is a BeforeHookFunc that also receives which step is executed.
+// step: zero-based index of the step
+// fnType: type of the function of the step, nil for steps without one such as built-in combinators
+// input: function parameters
+type BeforeIndexedHookFunc func(step int, fnType reflect.Type, input []interface{})
+
+// AfterIndexedHookFunc is an AfterHookFunc that also receives which step was executed, see BeforeIndexedHookFunc.
+type AfterIndexedHookFunc func(step int, fnType reflect.Type, input []interface{}, output []interface{})
+
+// ErrorIndexedHookFunc is an ErrorHookFunc that also receives which step failed, see BeforeIndexedHookFunc.
+type ErrorIndexedHookFunc func(step int, fnType reflect.Type, output []interface{}, err error)
+
+// MutatingBeforeHook is a hook function called before each function execution that can replace its arguments.
+// input: function parameters
+// Returning nil leaves the arguments unchanged.
+type MutatingBeforeHook func(input []interface{}) []interface{}
+
+// RecoverHookFunc is a hook function called when a function fails, it can turn the failure into a success.
+// output: function return values
+// err: function error
+// Returning a nil error recovers the chain, which continues with newOutput as the return values of the
+// function. Returning an error replaces err, e.g. with a wrapped error.
+type RecoverHookFunc func(output []interface{}, err error) (newOutput []interface{}, newErr error)
+
+// stepAfterHook is the form all after hooks are called in.
+type stepAfterHook func(step int, fnType reflect.Type, input, output []interface{}, d time.Duration)
+
 // New 创建一个新的函数链。
 // fns: 一个或多个待执行的函数。
 // 每个函数可以是任意类型，不限制参数和返回值的数量。
 // 如果传入的参数不是函数，则会被直接跳过，不会报错。
 func New(fns ...interface{}) *FunChain {
 	fc := &FunChain{
-		funcs:       make([]interface{}, 0, len(fns)),
+		steps:       make([]*step, 0, len(fns)),
 		defers:      make([]func(), 0),
-		beforeHooks: make([]BeforeHookFunc, 0),
-		afterHooks:  make([]AfterHookFunc, 0),
-		errHooks:    make([]ErrorHookFunc, 0),
+		beforeHooks: make([]beforeHook, 0),
+		afterHooks:  make([]afterHook, 0),
+		errHooks:    make([]errorHook, 0),
 	}
 	// 检查每个传入的参数，如果是函数则加入链中，否则跳过
 	for _, fn := range fns {
-		if reflect.TypeOf(fn).Kind() == reflect.Func {
-			fc.funcs = append(fc.funcs, fn)
+		if isStep(fn) {
+			fc.addStep(&step{fn: fn})
 		}
 	}
 	return fc
 }
 
+// addStep appends a step to the chain.
+func (fc *FunChain) addStep(st *step) {
+	fc.mu.Lock()
+	defer fc.unlock()
+	fc.steps = append(fc.steps, st)
+	if fc.onDuplicate != nil {
+		fc.checkDuplicate(len(fc.steps) - 1)
+	}
+}
+
+// isStep reports whether v can be added to a chain, i.e. v is a function, a Step or a non-nil *FunChain.
+func isStep(v interface{}) bool {
+	if sub, ok := v.(*FunChain); ok {
+		return sub != nil
+	}
+	if _, ok := v.(Step); ok {
+		return true
+	}
+	return isFunc(v)
+}
+
+// isFunc reports whether v is a function.
+func isFunc(v interface{}) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Func
+}
+
 // Then adds the next function to be executed.
 // fn: function to be executed.
 // fn can be any type of function, with no restrictions on the number of parameters and return values.
 // Return values from the previous function are automatically passed to the next function, except for errors.
+// Missing arguments are filled in with zero values and surplus ones are dropped, see StrictArity.
 // Parameter types between functions must be compatible.
 // Functions cannot return more than one error.
+// A *FunChain can also be added as a step, it receives the current arguments and its results are passed on.
 func (fc *FunChain) Then(fns ...interface{}) *FunChain {
 	for _, fn := range fns {
-		if reflect.TypeOf(fn).Kind() == reflect.Func { // 检查是否为函数类型
-			fc.funcs = append(fc.funcs, fn)
+		if isStep(fn) { // 检查是否为函数或子链
+			fc.addStep(&step{fn: fn})
+		}
+	}
+	return fc
+}
+
+// RawErrors makes the chain fail with the error of the failed step as it is, instead of wrapping it into
+// a StepError telling which step failed.
+func (fc *FunChain) RawErrors() *FunChain {
+	return fc.Configure(RawErrors())
+}
+
+// RawErrors returns an Option doing the same as FunChain.RawErrors.
+func RawErrors() Option {
+	return func(fc *FunChain) {
+		fc.rawErrors = true
+	}
+}
+
+// StrictArity makes a function fail with an error when it receives more arguments than it takes, instead of
+// dropping the surplus ones. Missing arguments are still filled in with zero values.
+func (fc *FunChain) StrictArity() *FunChain {
+	return fc.Configure(StrictArity())
+}
+
+// StrictArity returns an Option doing the same as FunChain.StrictArity.
+func StrictArity() Option {
+	return func(fc *FunChain) {
+		fc.strictArity = true
+	}
+}
+
+// ContinueOnError makes a failing function not abort the chain: its error is collected, the error hooks
+// are called, and the next function receives the results of the last function that succeeded.
+// Once all functions ran, Do returns the collected errors joined with errors.Join.
+// A done context still stops the chain.
+func (fc *FunChain) ContinueOnError() *FunChain {
+	return fc.Configure(ContinueOnError())
+}
+
+// ContinueOnError returns an Option doing the same as FunChain.ContinueOnError.
+func ContinueOnError() Option {
+	return func(fc *FunChain) {
+		fc.continueOnError = true
+	}
+}
+
+// WithMaxDepth limits how deep chains can be nested into each other, as a safety net against runaway
+// nesting that isn't cyclic. The chain itself is at depth 1, a chain nested into it at depth 2 and so on.
+// Running a nested chain beyond n fails with ErrMaxDepthExceeded.
+// The limit of the chain Do is called on applies to all nested chains, their own limits are ignored.
+// n: maximum depth, 0 means unlimited.
+func (fc *FunChain) WithMaxDepth(n int) *FunChain {
+	return fc.Configure(WithMaxDepth(n))
+}
+
+// WithMaxDepth returns an Option doing the same as FunChain.WithMaxDepth.
+func WithMaxDepth(n int) Option {
+	return func(fc *FunChain) {
+		fc.maxDepth = n
+	}
+}
+
+// InputFunc sets a function producing the arguments of the first function, evaluated exactly once at the
+// start of every Do rather than when the chain is built. It suits seeds that depend on state changing
+// between build and execution, or that are expensive to compute while the chain may not run.
+// The arguments are checked against the first function like the ones passed between functions.
+func (fc *FunChain) InputFunc(fn func() []interface{}) *FunChain {
+	return fc.Configure(InputFunc(fn))
+}
+
+// InputFunc returns an Option doing the same as FunChain.InputFunc.
+func InputFunc(fn func() []interface{}) Option {
+	return func(fc *FunChain) {
+		fc.input = fn
+	}
+}
+
+// Input sets the arguments of the first function, passed to it like the return values of a previous
+// function, so that the chain can be seeded without a closure. It replaces InputFunc and the other way round.
+func (fc *FunChain) Input(args ...interface{}) *FunChain {
+	return fc.Configure(Input(args...))
+}
+
+// Input returns an Option doing the same as FunChain.Input.
+func Input(args ...interface{}) Option {
+	args = append([]interface{}(nil), args...)
+	return InputFunc(func() []interface{} {
+		// 每次运行使用新的切片，避免被修改
+		return append([]interface{}(nil), args...)
+	})
+}
+
+// seedArgs returns the arguments of the first function of a run.
+func (fc *FunChain) seedArgs() []interface{} {
+	if fc.input == nil {
+		return nil
+	}
+	return fc.input()
+}
+
+// ThenPanicAsValue adds a function whose panic is not treated as a failure.
+// If fn panics, the recovered value is wrapped in a Panic and passed to the next function as its only argument,
+// otherwise the return values of fn are passed on as usual.
+func (fc *FunChain) ThenPanicAsValue(fn interface{}) *FunChain {
+	if isStep(fn) {
+		fc.addStep(&step{fn: fn, panicAsValue: true})
+	}
+	return fc
+}
+
+// ThenMapArgs adds a function whose incoming arguments are reshaped by transform before it is called,
+// e.g. to reorder or drop values at a single boundary without an extra adapter step.
+// transform receives a copy of the arguments, the arguments it returns must suit fn like any other arguments.
+func (fc *FunChain) ThenMapArgs(transform func([]interface{}) []interface{}, fn interface{}) *FunChain {
+	if isStep(fn) {
+		fc.addStep(&step{fn: fn, mapArgs: func(fc *FunChain, args []interface{}) []interface{} {
+			return transform(args)
+		}})
+	}
+	return fc
+}
+
+// ThenDefaults adds a function with default values for its parameters, used instead of the zero value when
+// the previous function supplied fewer arguments, which is finer-grained than WithZeroProvider.
+// defaults are matched to the parameters by position: defaults[i] is used for parameter i when fewer than
+// i+1 arguments came in, it never overrides an argument that was supplied. A nil default, or one not
+// assignable to its parameter, leaves that parameter to the usual zero value.
+// For example a function taking (url string, timeout time.Duration) added with ThenDefaults(fn, "", 30*time.Second)
+// gets a 30s timeout when only the url is supplied.
+func (fc *FunChain) ThenDefaults(fn interface{}, defaults ...interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fnType := reflect.TypeOf(fn)
+	fc.addStep(&step{fn: fn, mapArgs: func(fc *FunChain, args []interface{}) []interface{} {
+		for i := len(args); i < len(defaults) && i < fnType.NumIn(); i++ {
+			d := defaults[i]
+			if d == nil || !reflect.TypeOf(d).AssignableTo(fnType.In(i)) {
+				d = zeroValue(fnType.In(i), fc.zeroProvider).Interface()
+			}
+			args = append(args, d)
+		}
+		return args
+	}})
+	return fc
+}
+
+// ThenWithValue adds a function run with a context carrying the value val for key, scoped to that step only:
+// the steps after it don't see the value. It suits steps needing their own contextual parameters, e.g. a tag
+// or a deadline, such as the same function used twice with different settings.
+// fn: a function whose first parameter is a context.Context, which receives the context, followed by the
+// current arguments; or a Step or nested chain, which run with the context.
+// Context changes made by a ContextStep inside the step are discarded along with the value.
+func (fc *FunChain) ThenWithValue(key, val interface{}, fn interface{}) *FunChain {
+	if !isStep(fn) {
+		return fc
+	}
+	inner := &step{fn: fn}
+	fc.addStep(&step{fn: fn, kind: "WithValue", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		parent := rs.ctx
+		defer func() {
+			rs.ctx = parent
+		}()
+		rs.ctx = context.WithValue(parent, key, val)
+		return fc.execStep(rs, inner, args)
+	}})
+	return fc
+}
+
+// ThenDoc adds a function along with a human readable description of what it does.
+// The description is only metadata reported by Plan, it doesn't affect execution.
+func (fc *FunChain) ThenDoc(desc string, fn interface{}) *FunChain {
+	if isStep(fn) {
+		fc.addStep(&step{fn: fn, doc: desc})
+	}
+	return fc
+}
+
+// ThenNamed adds a function with a name, used instead of the name of the function wherever steps are named:
+// Plan, Describe, events, panic errors and StepName, e.g. for indexed hooks.
+func (fc *FunChain) ThenNamed(name string, fn interface{}) *FunChain {
+	if isStep(fn) {
+		fc.addStep(&step{fn: fn, label: name})
+	}
+	return fc
+}
+
+// ThenAlways adds a function that runs even if a prior step failed, like a finally block at a specific
+// position of the chain. Unlike Defer it takes part in the data flow.
+// fn: its first parameter must be of type error, it receives the error of the failed step, or nil when
+// no step failed, followed by the last good arguments, i.e. the arguments of the failed step.
+// When no step failed it behaves like a function added by Then.
+// After a failure, steps added by other methods are skipped and the chain still returns the original error,
+// along with the outputs of fn as results, unless fn clears it:
+//   - the error is cleared only if fn declares an error return value and returns nil,
+//     the chain then continues normally with the remaining steps, using the other outputs of fn as arguments;
+//   - if fn returns a non-nil error or panics, that error replaces the original one;
+//   - if fn has no error return value the error can't be cleared.
+func (fc *FunChain) ThenAlways(fn interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fnType := reflect.TypeOf(fn)
+	clearsErr := false
+	for i := 0; i < fnType.NumOut(); i++ {
+		if fnType.Out(i).Implements(errorType) {
+			clearsErr = true
+		}
+	}
+	always := func(fc *FunChain, failed error, args []interface{}) ([]interface{}, error) {
+		if fnType.NumIn() == 0 || fnType.In(0) != errorType {
+			return nil, fmt.Errorf("ThenAlways function must take an error as first parameter, got %s", fnType)
+		}
+		// 去掉 error 参数后按普通函数调用，剩余参数照常组装
+		errArg := reflect.Zero(errorType)
+		if failed != nil {
+			errArg = reflect.ValueOf(&failed).Elem()
+		}
+		in := make([]reflect.Type, 0, fnType.NumIn()-1)
+		for i := 1; i < fnType.NumIn(); i++ {
+			in = append(in, fnType.In(i))
+		}
+		out := make([]reflect.Type, 0, fnType.NumOut())
+		for i := 0; i < fnType.NumOut(); i++ {
+			out = append(out, fnType.Out(i))
 		}
+		fnValue := reflect.ValueOf(fn)
+		rest := reflect.MakeFunc(reflect.FuncOf(in, out, fnType.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+			if fnType.IsVariadic() {
+				return fnValue.CallSlice(append([]reflect.Value{errArg}, args...))
+			}
+			return fnValue.Call(append([]reflect.Value{errArg}, args...))
+		})
+		return execFunc(rest.Interface(), args, fc.zeroProvider)
 	}
+	fc.addStep(&step{fn: fn, kind: "Always", always: always, clearsErr: clearsErr, exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		return always(fc, nil, args)
+	}})
 	return fc
 }
 
 // Defer adds cleanup functions to be executed after the chain completes.
 // fs: list of defer functions.
 func (fc *FunChain) Defer(fs ...func()) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
 	fc.defers = append(fc.defers, fs...)
 	return fc
 }
@@ -76,157 +511,940 @@ func (fc *FunChain) Defer(fs ...func()) *FunChain {
 // Before adds hook functions to be called before each function execution.
 // hooks: list of before hook functions.
 func (fc *FunChain) Before(hooks ...BeforeHookFunc) *FunChain {
-	fc.beforeHooks = append(fc.beforeHooks, hooks...)
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.beforeHooks = append(fc.beforeHooks, beforeHook{fn: hook})
+	}
+	return fc
+}
+
+// BeforeIf adds a before hook that is only enabled when cond returns true.
+// cond is evaluated once at the start of every Do, so the hook can be toggled at runtime.
+func (fc *FunChain) BeforeIf(cond func() bool, hook BeforeHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.beforeHooks = append(fc.beforeHooks, beforeHook{fn: hook, cond: cond})
 	return fc
 }
 
 // After adds hook functions to be called after each function execution.
 // hooks: list of after hook functions.
 func (fc *FunChain) After(hooks ...AfterHookFunc) *FunChain {
-	fc.afterHooks = append(fc.afterHooks, hooks...)
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.afterHooks = append(fc.afterHooks, afterHook{fn: hook})
+	}
+	return fc
+}
+
+// AfterIf adds an after hook that is only enabled when cond returns true.
+// cond is evaluated once at the start of every Do.
+func (fc *FunChain) AfterIf(cond func() bool, hook AfterHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.afterHooks = append(fc.afterHooks, afterHook{fn: hook, cond: cond})
+	return fc
+}
+
+// AfterTimed adds hook functions to be called after each function execution with its execution time,
+// e.g. for profiling. Like the After hooks they are also called when the function failed.
+// hooks: list of timed after hook functions.
+func (fc *FunChain) AfterTimed(hooks ...TimedAfterHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.afterHooks = append(fc.afterHooks, afterHook{timed: hook})
+	}
+	return fc
+}
+
+// BeforeIndexed adds hook functions to be called before each function execution with the index and
+// type of the step, e.g. to tell apart steps sharing the same signature in logs.
+func (fc *FunChain) BeforeIndexed(hooks ...BeforeIndexedHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.beforeHooks = append(fc.beforeHooks, beforeHook{indexed: hook})
+	}
+	return fc
+}
+
+// AfterIndexed adds hook functions to be called after each function execution with the index and type of the step.
+func (fc *FunChain) AfterIndexed(hooks ...AfterIndexedHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.afterHooks = append(fc.afterHooks, afterHook{indexed: hook})
+	}
+	return fc
+}
+
+// OnErrorIndexed adds error handling functions receiving the index and type of the failed step.
+func (fc *FunChain) OnErrorIndexed(hooks ...ErrorIndexedHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.errHooks = append(fc.errHooks, errorHook{indexed: hook})
+	}
+	return fc
+}
+
+// BeforeMutate adds hook functions to be called before each function execution, whose results replace the
+// arguments of the function, e.g. to trim strings or clamp numbers. They are called in order, each with the
+// arguments returned by the previous one, before the Before hooks.
+// A hook must return as many arguments as it received, otherwise the function fails with an error.
+// hooks: list of mutating before hook functions.
+func (fc *FunChain) BeforeMutate(hooks ...MutatingBeforeHook) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		if hook != nil {
+			fc.mutateHooks = append(fc.mutateHooks, hook)
+		}
+	}
+	return fc
+}
+
+// Recover adds hook functions to be called when a function fails, before the error hooks, to degrade
+// gracefully, e.g. by replacing a "not found" error with a default value.
+// The hooks are called in order with the error returned by the previous hook, until one of them returns a
+// nil error. The chain then continues with the output of that hook and the error hooks are not called.
+// Otherwise the chain fails with the error of the last hook. The After hooks see the original output.
+// hooks: list of recover hook functions.
+func (fc *FunChain) Recover(hooks ...RecoverHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		if hook != nil {
+			fc.recoverHooks = append(fc.recoverHooks, hook)
+		}
+	}
 	return fc
 }
 
 // OnError adds error handling functions.
 // hooks: list of error handling functions.
 func (fc *FunChain) OnError(hooks ...ErrorHookFunc) *FunChain {
-	fc.errHooks = append(fc.errHooks, hooks...)
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	for _, hook := range hooks {
+		fc.errHooks = append(fc.errHooks, errorHook{fn: hook})
+	}
+	return fc
+}
+
+// ErrorHook is an alias of OnError, both add to the same list of error hooks.
+func (fc *FunChain) ErrorHook(hooks ...ErrorHookFunc) *FunChain {
+	return fc.OnError(hooks...)
+}
+
+// OnErrorIf adds an error hook that is only enabled when cond returns true.
+// cond is evaluated once at the start of every Do.
+func (fc *FunChain) OnErrorIf(cond func() bool, hook ErrorHookFunc) *FunChain {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	fc.errHooks = append(fc.errHooks, errorHook{fn: hook, cond: cond})
 	return fc
 }
 
+// HookTiming controls which arguments the Before hooks receive.
+type HookTiming int
+
+const (
+	// HookBeforeAssembly passes the Before hooks the arguments as returned by the previous function.
+	// This is the default.
+	HookBeforeAssembly HookTiming = iota
+	// HookAfterAssembly passes the Before hooks the arguments exactly as the function receives them,
+	// i.e. after ThenMapArgs transformations and with the missing arguments filled in.
+	// It helps debugging the zero-fill behavior.
+	HookAfterAssembly
+)
+
+// WithHookTiming sets which arguments the Before hooks receive, see HookTiming.
+func (fc *FunChain) WithHookTiming(timing HookTiming) *FunChain {
+	return fc.Configure(WithHookTiming(timing))
+}
+
+// WithHookTiming returns an Option doing the same as FunChain.WithHookTiming.
+func WithHookTiming(timing HookTiming) Option {
+	return func(fc *FunChain) {
+		fc.hookTiming = timing
+	}
+}
+
+// assembledArgs returns the arguments the function of st receives when called with args, after they were
+// transformed by the mapping of st if any. Steps other than plain functions receive args unchanged.
+func (fc *FunChain) assembledArgs(st *step, args []interface{}) []interface{} {
+	if st.exec != nil || !isFunc(st.fn) {
+		return args
+	}
+	in, err := callArgs(reflect.TypeOf(st.fn), args, fc.zeroProvider)
+	if err != nil {
+		// 参数类型不兼容，调用时会报错
+		return args
+	}
+	assembled := make([]interface{}, 0, len(in))
+	for _, v := range in {
+		assembled = append(assembled, v.Interface())
+	}
+	return assembled
+}
+
+// enabledHooks evaluates the hook conditions and returns the hooks enabled for this run.
+func (fc *FunChain) enabledHooks() (before []BeforeIndexedHookFunc, after []stepAfterHook, onErr []ErrorIndexedHookFunc) {
+	for _, h := range fc.beforeHooks {
+		if h.cond != nil && !h.cond() {
+			continue
+		}
+		if h.indexed != nil {
+			before = append(before, h.indexed)
+		} else if fn := h.fn; fn != nil {
+			before = append(before, func(step int, fnType reflect.Type, input []interface{}) {
+				fn(input)
+			})
+		}
+	}
+	for _, h := range fc.afterHooks {
+		if h.cond != nil && !h.cond() {
+			continue
+		}
+		switch {
+		case h.indexed != nil:
+			fn := h.indexed
+			after = append(after, func(step int, fnType reflect.Type, input, output []interface{}, d time.Duration) {
+				fn(step, fnType, input, output)
+			})
+		case h.timed != nil:
+			fn := h.timed
+			after = append(after, func(step int, fnType reflect.Type, input, output []interface{}, d time.Duration) {
+				fn(input, output, d)
+			})
+		case h.fn != nil:
+			fn := h.fn
+			after = append(after, func(step int, fnType reflect.Type, input, output []interface{}, d time.Duration) {
+				fn(input, output)
+			})
+		}
+	}
+	for _, h := range fc.errHooks {
+		if h.cond != nil && !h.cond() {
+			continue
+		}
+		if h.indexed != nil {
+			onErr = append(onErr, h.indexed)
+		} else if fn := h.fn; fn != nil {
+			onErr = append(onErr, func(step int, fnType reflect.Type, output []interface{}, err error) {
+				fn(output, err)
+			})
+		}
+	}
+	return before, after, onErr
+}
+
 // Do executes the function chain.
+// Every run keeps its state to itself, so a chain can be executed any number of times and running it again
+// with the same inputs gives the same results, unless its functions have side effects.
+// Do is safe to call concurrently as long as the functions of the chain are, see also Bind.
 // result: function return values
 // out: uses reflection to set return values to provided pointer variables.
 func (fc *FunChain) Do(out ...interface{}) (result []interface{}, err error) {
+	return fc.DoContext(context.Background(), out...)
+}
+
+// DoContext executes the function chain like Do with the context ctx.
+// ctx is checked before each function, once it is done the chain stops with ctx.Err(), which is passed to
+// the error hooks like any other error. A running function is not interrupted.
+// Functions whose first parameter is a context.Context receive ctx automatically, followed by the return
+// values of the previous function, unless the previous function already returned a context first.
+func (fc *FunChain) DoContext(ctx context.Context, out ...interface{}) (result []interface{}, err error) {
+	_, result, err = fc.doBound(ctx, out)
+	return result, err
+}
+
+// DoBound executes the function chain like Do and also reports how many out targets were populated,
+// so callers can detect that fewer results than expected came back.
+// The results are also bound when the chain completed despite partial failures, e.g. of ForkPartial branches.
+func (fc *FunChain) DoBound(out ...interface{}) (boundCount int, result []interface{}, err error) {
+	return fc.doBound(context.Background(), out)
+}
+
+// doBound executes the chain with the context ctx and binds the results to out.
+func (fc *FunChain) doBound(ctx context.Context, out []interface{}) (boundCount int, result []interface{}, err error) {
+	return fc.doSeeded(ctx, nil, out)
+}
+
+// doSeeded executes the chain with the context ctx and seed as the arguments of the first function,
+// or the arguments set with InputFunc if seed is nil, and binds the results to out.
+func (fc *FunChain) doSeeded(ctx context.Context, seed []interface{}, out []interface{}) (boundCount int, result []interface{}, err error) {
+	snap := fc.snapshot()
+	rs := newRunState(snap)
+	rs.ctx = ctx
+	if seed == nil {
+		seed = snap.seedArgs()
+	}
+	if snap.cache != nil {
+		return snap.executeCached(rs, seed, out)
+	}
+	return snap.execute(rs, 0, seed, out)
+}
+
+// DoWith executes the function chain like Do with in as the arguments of the first function,
+// instead of the ones set with Input or InputFunc.
+func (fc *FunChain) DoWith(in []interface{}, out ...interface{}) ([]interface{}, error) {
+	if in == nil {
+		// doSeeded 会把 nil 当作使用 Input 设置的参数
+		in = []interface{}{}
+	}
+	_, result, err := fc.doSeeded(context.Background(), in, out)
+	return result, err
+}
+
+// DoChecked executes the function chain like Do, but first checks that every entry of out is a non-nil
+// pointer and returns an error naming the offending index otherwise, without running the chain.
+func (fc *FunChain) DoChecked(out ...interface{}) ([]interface{}, error) {
+	for i, o := range out {
+		v := reflect.ValueOf(o)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return nil, fmt.Errorf("out[%d] must be a non-nil pointer, got %T", i, o)
+		}
+	}
+	return fc.Do(out...)
+}
+
+// MustDo executes the function chain like Do and returns the results, but panics if the chain fails,
+// e.g. in scripts and tests. The panic value is the error returned by Do, so it can be recovered and
+// inspected with errors.Is or errors.As. Defers and hooks run exactly as in Do, before the panic.
+func (fc *FunChain) MustDo(out ...interface{}) []interface{} {
+	result, err := fc.Do(out...)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+// snapshot returns a copy of the chain's configuration taken under its lock, runs work on the snapshot
+// so that builder methods called concurrently, e.g. Then, don't affect or race with runs in flight.
+// It is cheap: only the slices of steps and hooks are copied, not what they point to.
+func (fc *FunChain) snapshot() *FunChain {
+	if fc.origin != nil {
+		return fc
+	}
+	snap := fc.copyConfig()
+	snap.origin = fc
+	return snap
+}
+
+// Clone returns an independent copy of the chain with the same steps, hooks, defers and settings, so that
+// a base chain, e.g. with common hooks, can be used as a template: adding steps or hooks to the clone
+// doesn't affect the original and the other way round. Settings changed on the clone, such as WithClock or
+// WithFanOutLimit, apply to all its steps including combinators. The functions themselves are shared, not copied,
+// and the clone starts with empty stats and argument history.
+func (fc *FunChain) Clone() *FunChain {
+	return fc.self().copyConfig()
+}
+
+// copyConfig returns a copy of the chain's configuration taken under its lock.
+func (fc *FunChain) copyConfig() *FunChain {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return &FunChain{
+		steps:           append([]*step(nil), fc.steps...),
+		defers:          append(([]func())(nil), fc.defers...),
+		beforeHooks:     append([]beforeHook(nil), fc.beforeHooks...),
+		afterHooks:      append([]afterHook(nil), fc.afterHooks...),
+		errHooks:        append([]errorHook(nil), fc.errHooks...),
+		recoverHooks:    append([]RecoverHookFunc(nil), fc.recoverHooks...),
+		mutateHooks:     append([]MutatingBeforeHook(nil), fc.mutateHooks...),
+		redactor:        fc.redactor,
+		fanOutLimit:     fc.fanOutLimit,
+		zeroProvider:    fc.zeroProvider,
+		clock:           fc.clock,
+		abortAfter:      fc.abortAfter,
+		resumePanic:     fc.resumePanic,
+		watchdog:        fc.watchdog,
+		classifier:      fc.classifier,
+		onDuplicate:     fc.onDuplicate,
+		maxDepth:        fc.maxDepth,
+		name:            fc.name,
+		sinks:           append([]EventSink(nil), fc.sinks...),
+		hookTiming:      fc.hookTiming,
+		input:           fc.input,
+		cache:           fc.cache,
+		cacheTTL:        fc.cacheTTL,
+		cacheKey:        fc.cacheKey,
+		binder:          fc.binder,
+		argHistory:      fc.argHistory,
+		nameResolver:    fc.nameResolver,
+		streamBuffer:    fc.streamBuffer,
+		stepTimeout:     fc.stepTimeout,
+		onAbandoned:     fc.onAbandoned,
+		retries:         fc.retries,
+		retryBackoff:    fc.retryBackoff,
+		retryIf:         fc.retryIf,
+		continueOnError: fc.continueOnError,
+		strictArity:     fc.strictArity,
+		rawErrors:       fc.rawErrors,
+		logger:          fc.logger,
+		tracer:          fc.tracer,
+		released:        fc.released,
+	}
+}
+
+// self returns the chain a snapshot was taken from, or fc itself.
+func (fc *FunChain) self() *FunChain {
+	if fc.origin != nil {
+		return fc.origin
+	}
+	return fc
+}
+
+// execute runs the chain from the step at index from with args, updates the stats and binds the results to out.
+func (fc *FunChain) execute(rs *runState, from int, args []interface{}, out []interface{}) (boundCount int, result []interface{}, err error) {
+	result, err = fc.run(rs, from, args)
+	fc.self().recordRun(err)
+	if _, partial := err.(*partialError); err != nil && !partial {
+		return 0, result, err
+	}
+	boundCount, bindErr := fc.bind(result, out)
+	if err == nil {
+		err = bindErr
+	}
+	return boundCount, result, err
+}
+
+// bindResults sets the results to the pointers in out by position and returns the number of targets set.
+// Results are converted like arguments (see argValue), an error is returned for the first result that can't
+// be assigned to its target, the targets before it are set.
+func bindResults(args []interface{}, out []interface{}) (int, error) {
+	bound := 0
+	for i := 0; i < len(out); i++ {
+		if i >= len(args) {
+			break
+		}
+		if err := bindResult(i, args[i], out[i]); err == errNotSettable {
+			continue
+		} else if err != nil {
+			return bound, err
+		}
+		bound++
+	}
+	return bound, nil
+}
+
+// errNotSettable is returned by bindResult for targets that are not pointers, which are skipped.
+var errNotSettable = errors.New("out target can't be set")
+
+// bindResult sets the result at index i to the pointer out.
+func bindResult(i int, result interface{}, out interface{}) error {
+	dst := reflect.ValueOf(out)
+	if dst.Kind() == reflect.Ptr {
+		dst = dst.Elem()
+	}
+	if !dst.CanSet() {
+		return errNotSettable
+	}
+	// nil 结果设置为目标类型的零值
+	src, err := argValue(result, dst.Type())
+	if err != nil {
+		return fmt.Errorf("cannot assign result %d (%T) to %T", i, result, out)
+	}
+	dst.Set(src)
+	return nil
+}
+
+// runState holds the state shared by a chain and all of its nested chains during one Do.
+type runState struct {
+	// active contains the chains currently being executed, used to detect cyclic nesting.
+	active map[*FunChain]bool
+	// depth is the nesting depth of the chain being executed, 1 for the root chain.
+	depth int
+	// maxDepth is the maximum nesting depth set on the root chain, 0 means unlimited.
+	maxDepth int
+	// recording, if set, records the inputs of the steps of the root chain.
+	recording *Recording
+	// ctx is the context of the run, passed to Step implementations and functions taking a context.
+	ctx context.Context
+	// onStepDone, if set, receives the output of every successful step of the root chain.
+	onStepDone func(i int, output []interface{})
+}
+
+// newRunState creates the state of a run of the root chain.
+func newRunState(root *FunChain) *runState {
+	return &runState{
+		active:   make(map[*FunChain]bool),
+		maxDepth: root.maxDepth,
+		ctx:      context.Background(),
+	}
+}
+
+// fork returns a copy of the state for a branch executed in another goroutine.
+func (rs *runState) fork() *runState {
+	active := make(map[*FunChain]bool, len(rs.active))
+	for fc := range rs.active {
+		active[fc] = true
+	}
+	return &runState{
+		active:   active,
+		depth:    rs.depth,
+		maxDepth: rs.maxDepth,
+		ctx:      rs.ctx,
+	}
+}
+
+// run executes the function chain from the step at index from, with args as the arguments of that step.
+func (fc *FunChain) run(rs *runState, from int, args []interface{}) (result []interface{}, err error) {
+	if fc.released {
+		return nil, ErrReleased
+	}
+	rs.active[fc.self()] = true
+	rs.depth++
+	defer func() {
+		delete(rs.active, fc.self())
+		rs.depth--
+	}()
 	// Register all defer functions (will execute in LIFO order)
-	for _, fn := range fc.defers {
-		defer func(fn func()) {
+	for i, fn := range fc.defers {
+		defer func(i int, fn func()) {
+			deferStart := fc.now()
 			// Protect against panic in a defer function.
 			defer func() {
+				var err error
 				if r := recover(); r != nil {
 					fmt.Println("Panic from defer hook:", r)
+					err = newPanicError("defer", r)
+				}
+				if len(fc.sinks) > 0 {
+					fc.emit(Event{Type: EventDefer, Chain: fc.name, Step: i, Duration: fc.now().Sub(deferStart), Err: err})
 				}
 			}()
 			fn()
-		}(fn)
+		}(i, fn)
 	}
-	var args []interface{}
-	var args2 []interface{}
-	for _, fn := range fc.funcs {
-		// Execute all Before hooks with recovery protection.
-		for _, hook := range fc.beforeHooks {
-			if hook == nil {
+	beforeHooks, afterHooks, errHooks := fc.enabledHooks()
+	start := fc.now()
+	if len(fc.sinks) > 0 {
+		defer func() {
+			fc.emit(Event{Type: EventChainEnd, Chain: fc.name, Step: -1, Duration: fc.now().Sub(start), Err: err})
+		}()
+	}
+	var history [][]interface{}
+	if fc.argHistory && rs.depth == 1 {
+		defer func() {
+			for len(history) < len(fc.steps) {
+				history = append(history, nil)
+			}
+			fc.self().setArgHistory(history)
+		}()
+	}
+	var wd *watchdog
+	if fc.watchdog != nil {
+		wd = startWatchdog(fc.watchdog, fc.getClock())
+		defer wd.stop()
+	}
+	var (
+		args2   []interface{}
+		partial []error
+		// failed 是出错后尚未被 ThenAlways 清除的错误，此时只执行 ThenAlways 步骤
+		failed    error
+		failArgs  []interface{}
+		failIndex int
+		// completed 是已完成的可补偿步骤，出错时逆序补偿
+		completed []compensable
+		// continued 是 ContinueOnError 模式下收集的错误
+		continued []error
+	)
+	for i := from; i < len(fc.steps); i++ {
+		st := fc.steps[i]
+		if failed != nil && st.always == nil {
+			continue
+		}
+		if rs.recording != nil && rs.depth == 1 {
+			rs.recording.record(i, args)
+		}
+		if wd != nil {
+			wd.enter(i, fc.stepName(st))
+		}
+		if fc.abortAfter > 0 && fc.now().Sub(start) > fc.abortAfter {
+			// 超出时间预算，返回最后一次成功的结果
+			break
+		}
+		if failed == nil {
+			if err = rs.ctx.Err(); err != nil {
+				// context 已结束，不再执行后续的函数
+				failed, failArgs, failIndex = fc.stepFailed(rs, i, nil, err, errHooks), nil, i
+				continue
+			}
+		}
+		if st.cond != nil && failed == nil && !fc.runsStep(st, args) {
+			// 条件不满足，跳过该步骤，参数原样传给下一步
+			continue
+		}
+		if len(fc.mutateHooks) > 0 {
+			if args, err = fc.mutateArgs(i, args); err != nil {
+				if fc.continueOnError && failed == nil {
+					continued = append(continued, fc.stepError(i, fc.stepFailed(rs, i, args, err, errHooks)))
+					continue
+				}
+				failed, failArgs, failIndex = fc.stepFailed(rs, i, args, err, errHooks), args, i
 				continue
 			}
+		}
+		// 参数映射只执行一次，执行时使用不再映射参数的步骤副本
+		execSt, mapped := st, args
+		if st.mapArgs != nil && failed == nil {
+			mapped = st.mapArgs(fc, append([]interface{}(nil), args...))
+			copied := *st
+			copied.mapArgs = nil
+			execSt = &copied
+		}
+		hookArgs := args
+		if fc.hookTiming == HookAfterAssembly && len(beforeHooks) > 0 {
+			hookArgs = fc.assembledArgs(st, mapped)
+		}
+		if fc.argHistory && rs.depth == 1 {
+			history = recordArgs(history, i, fc.assembledArgs(st, mapped))
+		}
+		// Execute all Before hooks with recovery protection.
+		for _, hook := range beforeHooks {
 			func() {
 				defer func() {
 					if r := recover(); r != nil {
 						fmt.Println("Panic from before hook:", r)
 					}
 				}()
-				hook(args)
+				hook(i, reflect.TypeOf(st.fn), fc.redact(i, hookArgs))
 			}()
 		}
-		args2, err = execFunc(fn, args)
-		// Execute all After hooks with recovery protection.
-		for _, hook := range fc.afterHooks {
-			if hook == nil {
-				continue
+		stepStart := fc.now()
+		if len(fc.sinks) > 0 {
+			fc.emit(Event{Type: EventStepStart, Chain: fc.name, Step: i, StepName: fc.stepName(st)})
+		}
+		var endSpan func(error)
+		if fc.tracer != nil {
+			endSpan = fc.startSpan(rs, st)
+		}
+		if failed != nil {
+			args2, err = st.always(fc, failed, args)
+		} else {
+			args2, err = fc.execStepRetry(rs, i, execSt, mapped)
+		}
+		if endSpan != nil {
+			endSpan(err)
+		}
+		stepDuration := fc.now().Sub(stepStart)
+		if len(fc.sinks) > 0 {
+			fc.emit(Event{Type: EventStepEnd, Chain: fc.name, Step: i, StepName: fc.stepName(st), Duration: stepDuration, Err: err})
+		}
+		if fc.logger != nil {
+			fc.logStep(i, st, args, args2, stepDuration, err)
+		}
+		if err == nil && len(args2) == 0 && i > 0 && fc.steps[i-1].bind != nil {
+			// 紧随 Bind 的步骤没有返回值时，把结构体拆回参数
+			args2 = unpackStruct(fc.steps[i-1].bind)
+		}
+		if errors.Is(err, ErrStopChain) {
+			// 干净地结束函数链，返回当前的参数
+			return args, partialResult(partial)
+		}
+		if pe, ok := err.(*partialError); ok {
+			// 部分失败不中断函数链，错误在最后一并返回
+			partial = append(partial, pe.errs...)
+			err = nil
+		}
+		if pe := (*PanicError)(nil); errors.As(err, &pe) && fc.resumePanic != nil {
+			if resumeArgs, ok := fc.resumeFromPanic(i, pe.value); ok {
+				args2, err = resumeArgs, nil
 			}
+		}
+		// Execute all After hooks with recovery protection.
+		for _, hook := range afterHooks {
 			func() {
 				defer func() {
 					if r := recover(); r != nil {
 						fmt.Println("Panic from after hook:", r)
 					}
 				}()
-				hook(args, args2)
+				hook(i, reflect.TypeOf(st.fn), fc.redact(i, args), fc.redact(i, args2), stepDuration)
 			}()
 		}
-		if err != nil {
-			for _, hook := range fc.errHooks {
-				if hook == nil {
-					continue
-				}
-				func() {
-					defer func() {
-						if r := recover(); r != nil {
-							// Optionally log or ignore panic from error hook.
-						}
-					}()
-					hook(args2, err)
-				}()
+		if err != nil && len(fc.recoverHooks) > 0 {
+			args2, err = fc.recoverStep(args2, err)
+		}
+		if failed != nil && err == nil {
+			args, failArgs = args2, args2
+			if st.clearsErr {
+				failed = nil
 			}
-			return args2, err
+			continue
+		}
+		if err != nil && fc.continueOnError && failed == nil {
+			// 记录错误，用上一步成功的结果继续执行
+			continued = append(continued, fc.stepError(i, fc.stepFailed(rs, i, args2, err, errHooks)))
+			continue
+		}
+		if err != nil {
+			failed, failArgs, failIndex = fc.stepFailed(rs, i, args2, err, errHooks), args2, i
+			continue
+		}
+		if st.compensation != nil {
+			completed = append(completed, compensable{index: i, fn: st.compensation, outputs: args2})
+		}
+		if rs.onStepDone != nil && rs.depth == 1 {
+			rs.onStepDone(i, args2)
 		}
 		args = args2
 	}
-	for i := 0; i < len(out); i++ {
-		if i >= len(args) {
-			break
-		}
-		src := reflect.ValueOf(args[i])
-		dst := reflect.ValueOf(out[i])
-		if dst.Kind() == reflect.Ptr {
-			dst = dst.Elem()
+	if failed != nil {
+		failed = fc.stepError(failIndex, failed)
+		if len(continued) > 0 {
+			// ctx 结束等无法继续的错误与之前收集的错误合并
+			failed = errors.Join(append(continued, failed)...)
 		}
-		if !dst.CanSet() {
+		return failArgs, fc.compensate(completed, failed)
+	}
+	if len(continued) > 0 {
+		return args, fc.compensate(completed, errors.Join(append(continued, partial...)...))
+	}
+	return args, partialResult(partial)
+}
+
+// StepError is the error a chain fails with, it tells which step failed and wraps the error of the step,
+// so that errors.Is and errors.As see through it. Its message is the one of the wrapped error.
+// See RawErrors to get the errors of the steps as they are.
+type StepError struct {
+	index  int
+	fnType reflect.Type
+	err    error
+}
+
+func (e *StepError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error of the step.
+func (e *StepError) Unwrap() error {
+	return e.err
+}
+
+// Index returns the zero-based index of the failed step.
+func (e *StepError) Index() int {
+	return e.index
+}
+
+// FuncType returns the type of the function of the failed step, nil for steps without one such as
+// built-in combinators.
+func (e *StepError) FuncType() reflect.Type {
+	return e.fnType
+}
+
+// stepError wraps the error of the step at index i into a StepError, unless RawErrors is set.
+func (fc *FunChain) stepError(i int, err error) error {
+	if fc.rawErrors {
+		return err
+	}
+	return &StepError{index: i, fnType: reflect.TypeOf(fc.steps[i].fn), err: err}
+}
+
+// mutateArgs passes the arguments of the step at index i through the mutating before hooks.
+func (fc *FunChain) mutateArgs(i int, args []interface{}) ([]interface{}, error) {
+	for _, hook := range fc.mutateHooks {
+		var mutated []interface{}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Println("Panic from before mutate hook:", r)
+				}
+			}()
+			mutated = hook(append([]interface{}(nil), args...))
+		}()
+		if mutated == nil {
 			continue
 		}
-		dst.Set(src)
+		if len(mutated) != len(args) {
+			return args, fmt.Errorf("before mutate hook changed the number of arguments of step %d from %d to %d", i, len(args), len(mutated))
+		}
+		args = mutated
 	}
 	return args, nil
 }
 
+// recoverStep passes the output and error of a failed function through the recover hooks.
+func (fc *FunChain) recoverStep(output []interface{}, err error) ([]interface{}, error) {
+	for _, hook := range fc.recoverHooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					fmt.Println("Panic from recover hook:", r)
+				}
+			}()
+			newOutput, newErr := hook(output, err)
+			if newErr == nil {
+				output = newOutput
+			}
+			err = newErr
+		}()
+		if err == nil {
+			break
+		}
+	}
+	return output, err
+}
+
+// stepFailed classifies the error of the step at index i, records it and calls the error hooks.
+// It returns the classified error.
+func (fc *FunChain) stepFailed(rs *runState, i int, args []interface{}, err error, errHooks []ErrorIndexedHookFunc) error {
+	err = fc.classify(err)
+	if rs.recording != nil && rs.depth == 1 {
+		rs.recording.FailedStep, rs.recording.Err = i, err.Error()
+	}
+	for _, hook := range errHooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					// Optionally log or ignore panic from error hook.
+				}
+			}()
+			hook(i, reflect.TypeOf(fc.steps[i].fn), fc.redact(i, args), err)
+		}()
+	}
+	return err
+}
+
+// ResumePanicFunc decides whether the chain continues after the function at index step panicked.
+// recovered: the value recovered from the panic.
+// It returns the arguments for the next function and true to resume, or false to fail the chain as usual.
+type ResumePanicFunc func(step int, recovered interface{}) (resumeArgs []interface{}, ok bool)
+
+// WithResumablePanic sets a handler that can resume the chain after a function panicked,
+// by substituting resumeArgs for the return values of the crashed function.
+// Use with care: the panicking function may have left shared state inconsistent, resuming is only safe
+// when the handler knows the function well enough to supply a sensible replacement. It is disabled by default.
+func (fc *FunChain) WithResumablePanic(handler ResumePanicFunc) *FunChain {
+	return fc.Configure(WithResumablePanic(handler))
+}
+
+// WithResumablePanic returns an Option doing the same as FunChain.WithResumablePanic.
+func WithResumablePanic(handler ResumePanicFunc) Option {
+	return func(fc *FunChain) {
+		fc.resumePanic = handler
+	}
+}
+
+// resumeFromPanic calls the resumable panic handler, a panic in the handler is treated as a refusal to resume.
+func (fc *FunChain) resumeFromPanic(step int, recovered interface{}) (resumeArgs []interface{}, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Panic from resumable panic handler:", r)
+			resumeArgs, ok = nil, false
+		}
+	}()
+	return fc.resumePanic(step, recovered)
+}
+
+// execStep executes a single step of the chain, which is either a function or a nested chain.
+func (fc *FunChain) execStep(rs *runState, st *step, args []interface{}) ([]interface{}, error) {
+	if st.mapArgs != nil {
+		args = st.mapArgs(fc, append([]interface{}(nil), args...))
+	}
+	if sub, ok := st.fn.(*FunChain); ok {
+		if rs.active[sub] {
+			return nil, ErrCyclicChain
+		}
+		if rs.maxDepth > 0 && rs.depth >= rs.maxDepth {
+			return nil, fmt.Errorf("%w: limit is %d", ErrMaxDepthExceeded, rs.maxDepth)
+		}
+		return sub.snapshot().run(rs, 0, args)
+	}
+	var (
+		result []interface{}
+		err    error
+	)
+	if st.exec != nil {
+		result, err = fc.execCombinator(rs, st, args)
+	} else if s, ok := st.fn.(Step); ok {
+		result, err = execStepObject(rs, s, args)
+	} else {
+		in := injectContext(rs.ctx, st.fn, args)
+		if t := reflect.TypeOf(st.fn); fc.strictArity && !t.IsVariadic() && len(in) > t.NumIn() {
+			return nil, fmt.Errorf("function %s takes %d arguments, got %d", fc.stepName(st), t.NumIn(), len(in))
+		}
+		result, err = execFunc(st.fn, in, fc.zeroProvider)
+	}
+	if pe := (*PanicError)(nil); errors.As(err, &pe) && st.panicAsValue {
+		return []interface{}{Panic{Value: pe.value}}, nil
+	}
+	if pe, ok := err.(*PanicError); ok && st.exec == nil && isFunc(st.fn) {
+		pe.funcName = fc.stepName(st)
+	}
+	return result, err
+}
+
+// execCombinator runs the exec function of st, a panic fails the step with a PanicError like for functions.
+func (fc *FunChain) execCombinator(rs *runState, st *step, args []interface{}) (result []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, newPanicError(fc.stepName(st), r)
+		}
+	}()
+	return st.exec(fc, rs, args)
+}
+
+// injectContext returns args preceded by ctx if the first parameter of fn is a context.Context and args
+// doesn't start with a context already.
+func injectContext(ctx context.Context, fn interface{}, args []interface{}) []interface{} {
+	t := reflect.TypeOf(fn)
+	if t.NumIn() == 0 || t.In(0) != contextType {
+		return args
+	}
+	if len(args) > 0 {
+		if _, ok := args[0].(context.Context); ok {
+			return args
+		}
+	}
+	return append([]interface{}{ctx}, args...)
+}
+
 // execFunc executes a function with given arguments.
 // f: function to be executed.
 // args: arguments to pass to the function.
+// zero: provides the values of missing arguments, may be nil.
 // returns: function return values and an error if any.
-func execFunc(f interface{}, args []interface{}) ([]interface{}, error) {
+func execFunc(f interface{}, args []interface{}, zero ZeroProvider) ([]interface{}, error) {
 	funcType := reflect.TypeOf(f)
-	if funcType.Kind() != reflect.Func {
+	if funcType == nil || funcType.Kind() != reflect.Func {
 		return nil, errors.New("not a function")
 	}
-	errIndex := -1
-	for i := 0; i < funcType.NumOut(); i++ {
-		if funcType.Out(i).Implements(reflect.TypeOf((*error)(nil)).Elem()) {
-			if errIndex != -1 {
-				return nil, errors.New("more than one error")
-			}
-			errIndex = i
-		}
-	}
-	funcValue := reflect.ValueOf(f)
-	rf := reflect.MakeFunc(funcType, func(callArgs []reflect.Value) []reflect.Value {
-		return funcValue.Call(callArgs)
-	})
-	in := make([]reflect.Value, 0, funcType.NumIn())
-	// Pass the return values from the previous function as arguments to the next function.
-	for _, arg := range args {
-		in = append(in, reflect.ValueOf(arg))
+	meta := funcMetaOf(funcType)
+	if meta.multiErr {
+		return nil, errors.New("more than one error")
 	}
-	// If there are fewer arguments than parameters, create zero values for the missing ones.
-	for i := len(args); i < funcType.NumIn(); i++ {
-		// 此处使用 reflect.Zero 获取参数对应类型的零值，确保如果传入的参数数量不足时，自动填充默认值。
-		// 例如，int 类型将补上 0，string 类型则补上 ""，从而保证函数调用的参数数量与签名一致。
-		in = append(in, reflect.Zero(funcType.In(i)))
+	in, err := callArgs(funcType, args, zero)
+	if err != nil {
+		return nil, err
 	}
+	funcValue := reflect.ValueOf(f)
 	var out []reflect.Value
-	var err error
 	// 捕获 panic 并返回错误
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-				err = fmt.Errorf("panic from function %s: %v", funcType.Name(), r)
+				err = newPanicError(funcType.Name(), r)
 			}
 		}()
-		out = rf.Call(in)
+		if meta.variadic {
+			out = funcValue.CallSlice(in)
+		} else {
+			out = funcValue.Call(in)
+		}
 	}()
 	if err != nil {
 		return nil, err
 	}
-	result := make([]interface{}, 0, 1)
+	result := make([]interface{}, 0, len(out))
 	for i, returnVal := range out {
-		if i == errIndex {
+		if i == meta.errIndex {
 			if e, ok := returnVal.Interface().(error); ok {
 				err = e
 			}
@@ -236,3 +1454,177 @@ func execFunc(f interface{}, args []interface{}) ([]interface{}, error) {
 	}
 	return result, err
 }
+
+// funcMeta is the reflection metadata execFunc needs about a function type.
+type funcMeta struct {
+	// errIndex is the index of the error result, -1 if there is none.
+	errIndex int
+	// multiErr reports whether the function returns more than one error.
+	multiErr bool
+	variadic bool
+}
+
+// funcMetas caches the funcMeta of every function type executed, keyed by reflect.Type.
+var funcMetas sync.Map
+
+// funcMetaOf returns the metadata of the function type t, computing it on first use.
+func funcMetaOf(t reflect.Type) *funcMeta {
+	if meta, ok := funcMetas.Load(t); ok {
+		return meta.(*funcMeta)
+	}
+	meta := &funcMeta{errIndex: -1, variadic: t.IsVariadic()}
+	for i := 0; i < t.NumOut(); i++ {
+		if t.Out(i).Implements(errorType) {
+			if meta.errIndex != -1 {
+				meta.multiErr = true
+			}
+			meta.errIndex = i
+		}
+	}
+	funcMetas.Store(t, meta)
+	return meta
+}
+
+// callArgs assembles the arguments actually passed to a function of type funcType from args.
+// For a variadic function the trailing arguments are collected into the slice of the variadic parameter,
+// which is the last value returned, so the function must be called with CallSlice.
+// An error is returned if an argument can't be passed as the corresponding parameter.
+func callArgs(funcType reflect.Type, args []interface{}, zero ZeroProvider) ([]reflect.Value, error) {
+	fixed := funcType.NumIn()
+	if funcType.IsVariadic() {
+		fixed--
+	}
+	in := make([]reflect.Value, 0, funcType.NumIn())
+	// Pass the return values from the previous function as arguments to the next function.
+	for i, arg := range args {
+		if funcType.IsVariadic() && i >= fixed {
+			break
+		}
+		if i >= funcType.NumIn() {
+			// 多余的参数直接丢弃，与缺少参数时补零值的宽松处理一致
+			break
+		}
+		v, err := argValue(arg, funcType.In(i))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		in = append(in, v)
+	}
+	// If there are fewer arguments than parameters, create zero values for the missing ones.
+	for i := len(args); i < fixed; i++ {
+		// 此处使用 reflect.Zero 获取参数对应类型的零值，确保如果传入的参数数量不足时，自动填充默认值。
+		// 例如，int 类型将补上 0，string 类型则补上 ""，从而保证函数调用的参数数量与签名一致。
+		in = append(in, zeroValue(funcType.In(i), zero))
+	}
+	if funcType.IsVariadic() {
+		// 剩余的参数收集到可变参数的切片中，没有剩余参数时为空切片
+		sliceType := funcType.In(fixed)
+		rest := reflect.MakeSlice(sliceType, 0, len(args))
+		for i := fixed; i < len(args); i++ {
+			v, err := argValue(args[i], sliceType.Elem())
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %w", i, err)
+			}
+			rest = reflect.Append(rest, v)
+		}
+		in = append(in, rest)
+	}
+	return in, nil
+}
+
+// argValue returns arg as the value of a parameter of type t.
+// Values assignable to t, e.g. a *os.File for an io.Reader, are passed as they are, other values convertible
+// to t, e.g. between numeric types or named types with the same underlying type, are converted.
+// Integers are not converted to strings since that yields the character of the code point, not the number.
+func argValue(arg interface{}, t reflect.Type) (value reflect.Value, err error) {
+	if arg == nil {
+		// nil 没有类型，reflect.ValueOf 得到的是无效值，使用参数类型的零值（如 nil 指针）代替
+		return reflect.Zero(t), nil
+	}
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+	if v.Type().ConvertibleTo(t) && !(isInteger(v.Kind()) && t.Kind() == reflect.String) {
+		defer func() {
+			// 切片转换为数组时长度不足会 panic
+			if r := recover(); r != nil {
+				err = fmt.Errorf("cannot convert %s to %s: %v", v.Type(), t, r)
+			}
+		}()
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %s as %s", v.Type(), t)
+}
+
+// isInteger reports whether k is the kind of an integer type.
+func isInteger(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// ZeroProvider supplies the value of a missing argument of type t.
+// It returns false to fall back to the zero value of t.
+type ZeroProvider func(t reflect.Type) (reflect.Value, bool)
+
+// WithZeroProvider sets the provider of the values used to fill missing arguments,
+// e.g. to pass context.Background() instead of a nil context.Context.
+// Arguments returned by the previous function always take precedence, the provider is only
+// consulted when a function has more parameters than the arguments it receives.
+// Values that are not assignable to the parameter type are ignored in favor of the zero value.
+func (fc *FunChain) WithZeroProvider(provider ZeroProvider) *FunChain {
+	return fc.Configure(WithZeroProvider(provider))
+}
+
+// WithZeroProvider returns an Option doing the same as FunChain.WithZeroProvider.
+func WithZeroProvider(provider ZeroProvider) Option {
+	return func(fc *FunChain) {
+		fc.zeroProvider = provider
+	}
+}
+
+// zeroValue returns the value used to fill a missing argument of type t.
+func zeroValue(t reflect.Type, zero ZeroProvider) reflect.Value {
+	if zero != nil {
+		if v, ok := zero(t); ok && v.IsValid() && v.Type().AssignableTo(t) {
+			return v
+		}
+	}
+	return reflect.Zero(t)
+}
+
+// PanicError is the error a chain fails with when a function panics, it can be retrieved with errors.As.
+type PanicError struct {
+	funcName string
+	value    interface{}
+	stack    []byte
+}
+
+// newPanicError returns the PanicError for the value r recovered from a panic of the function named funcName.
+// It must be called in the deferred function that recovered r to capture the stack of the panic.
+func newPanicError(funcName string, r interface{}) *PanicError {
+	return &PanicError{funcName: funcName, value: r, stack: debug.Stack()}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic from function %s: %v", e.funcName, e.value)
+}
+
+// Is reports whether target is ErrPanic.
+func (e *PanicError) Is(target error) bool {
+	return target == ErrPanic
+}
+
+// Value returns the value the function panicked with.
+func (e *PanicError) Value() interface{} {
+	return e.value
+}
+
+// Stack returns the stack trace of the goroutine at the time of the panic, as formatted by runtime/debug.Stack.
+func (e *PanicError) Stack() []byte {
+	return e.stack
+}
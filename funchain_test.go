@@ -1,11 +1,17 @@
 package funchain
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFuncChain(t *testing.T) {
@@ -188,3 +194,1144 @@ func TestFunctionPanic(t *testing.T) {
 		}
 	})
 }
+
+func TestConditionalHooks(t *testing.T) {
+	var (
+		debug       bool
+		beforeCount int
+		afterCount  int
+		errCount    int
+	)
+	fc := New(func() error {
+		return errors.New("failed")
+	}).BeforeIf(func() bool { return debug }, func(input []interface{}) {
+		beforeCount++
+	}).AfterIf(func() bool { return debug }, func(input []interface{}, output []interface{}) {
+		afterCount++
+	}).OnErrorIf(func() bool { return debug }, func(output []interface{}, err error) {
+		errCount++
+	})
+
+	_, _ = fc.Do()
+	if beforeCount != 0 || afterCount != 0 || errCount != 0 {
+		t.Fatalf("hooks should be disabled: before=%d, after=%d, err=%d", beforeCount, afterCount, errCount)
+	}
+
+	debug = true
+	_, _ = fc.Do()
+	if beforeCount != 1 || afterCount != 1 || errCount != 1 {
+		t.Fatalf("hooks should be enabled: before=%d, after=%d, err=%d", beforeCount, afterCount, errCount)
+	}
+}
+
+func TestNestedChain(t *testing.T) {
+	var result int
+	sub := New(func(n int) int {
+		return n + 1
+	}, func(n int) int {
+		return n * 2
+	})
+	_, err := New(func() int {
+		return 3
+	}).Then(sub).Then(func(n int) int {
+		return n - 1
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 7 {
+		t.Fatalf("unexpected result: expected 7, got %d", result)
+	}
+
+	// 同一个子链顺序出现多次不属于循环嵌套
+	_, err = New(func() int { return 1 }).Then(sub, sub).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 10 {
+		t.Fatalf("unexpected result: expected 10, got %d", result)
+	}
+}
+
+func TestCyclicChain(t *testing.T) {
+	// 直接引用自身
+	fc := New(func() int { return 1 })
+	fc.Then(fc)
+	_, err := fc.Do()
+	if !errors.Is(err, ErrCyclicChain) {
+		t.Fatalf("expected ErrCyclicChain, got %v", err)
+	}
+
+	// 通过其他子链间接引用自身
+	a := New(func() int { return 1 })
+	b := New(func(n int) int { return n })
+	a.Then(b)
+	b.Then(a)
+	errCalled := false
+	a.OnError(func(output []interface{}, err error) {
+		errCalled = true
+	})
+	_, err = a.Do()
+	if !errors.Is(err, ErrCyclicChain) {
+		t.Fatalf("expected ErrCyclicChain, got %v", err)
+	}
+	if !errCalled {
+		t.Fatal("error hook was not called for cyclic chain")
+	}
+}
+
+func TestPanicAsValue(t *testing.T) {
+	var p Panic
+	_, err := New(func() int {
+		return 1
+	}).ThenPanicAsValue(func(n int) int {
+		panic("boom")
+	}).Then(func(p Panic) Panic {
+		return p
+	}).Do(&p)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if p.Value != "boom" {
+		t.Fatalf("unexpected panic value: %v", p.Value)
+	}
+
+	// 未发生 panic 时返回值正常传递
+	var result int
+	_, err = New(func() int {
+		return 1
+	}).ThenPanicAsValue(func(n int) int {
+		return n + 1
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 2 {
+		t.Fatalf("unexpected result: expected 2, got %d", result)
+	}
+}
+
+func TestZeroProvider(t *testing.T) {
+	var (
+		name  string
+		count int
+	)
+	_, err := New(func() string {
+		return "foo"
+	}).Then(func(s string, n int, w io.Writer) (string, int) {
+		if w != os.Stdout {
+			t.Fatal("missing io.Writer should be provided")
+		}
+		return s, n
+	}).WithZeroProvider(func(t reflect.Type) (reflect.Value, bool) {
+		switch t {
+		case reflect.TypeOf((*io.Writer)(nil)).Elem():
+			return reflect.ValueOf(os.Stdout), true
+		case reflect.TypeOf(""):
+			return reflect.ValueOf("default"), true
+		}
+		return reflect.Value{}, false
+	}).Do(&name, &count)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 已有参数优先于提供的默认值，未提供的类型回退为零值
+	if name != "foo" || count != 0 {
+		t.Fatalf("unexpected results: name=%s, count=%d", name, count)
+	}
+}
+
+func TestResumablePanic(t *testing.T) {
+	var (
+		result    int
+		panicStep = -1
+	)
+	handler := func(step int, recovered interface{}) ([]interface{}, bool) {
+		panicStep = step
+		return []interface{}{100}, recovered == "recoverable"
+	}
+	_, err := New(func() int {
+		return 1
+	}).Then(func(n int) int {
+		panic("recoverable")
+	}).Then(func(n int) int {
+		return n + 1
+	}).WithResumablePanic(handler).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if panicStep != 1 || result != 101 {
+		t.Fatalf("unexpected state: panicStep=%d, result=%d", panicStep, result)
+	}
+
+	// 处理函数拒绝恢复时按原有方式失败
+	_, err = New(func() int {
+		panic("fatal")
+	}).WithResumablePanic(handler).Do()
+	if err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+}
+
+func TestMapArgs(t *testing.T) {
+	var result string
+	_, err := New(func() (int, string) {
+		return 3, "x"
+	}).ThenMapArgs(func(args []interface{}) []interface{} {
+		// 交换参数顺序
+		return []interface{}{args[1], args[0]}
+	}, func(s string, n int) string {
+		return strings.Repeat(s, n)
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "xxx" {
+		t.Fatalf("unexpected result: expected 'xxx', got %q", result)
+	}
+}
+
+func TestDoBound(t *testing.T) {
+	var (
+		a, b, c int
+	)
+	bound, result, err := New(func() (int, int) {
+		return 1, 2
+	}).DoBound(&a, &b, &c)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if bound != 2 || len(result) != 2 {
+		t.Fatalf("unexpected bound count: expected 2, got %d", bound)
+	}
+	if a != 1 || b != 2 || c != 0 {
+		t.Fatalf("unexpected values: a=%d, b=%d, c=%d", a, b, c)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	// 构造深度为 5 的嵌套链
+	fc := New(func(n int) int {
+		return n + 1
+	})
+	for i := 0; i < 4; i++ {
+		fc = New(func(n int) int {
+			return n
+		}).Then(fc)
+	}
+	var result int
+	if _, err := fc.WithMaxDepth(5).Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 1 {
+		t.Fatalf("unexpected result: expected 1, got %d", result)
+	}
+	if _, err := fc.WithMaxDepth(4).Do(); !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Fatalf("expected ErrMaxDepthExceeded, got %v", err)
+	}
+}
+
+func TestHookTiming(t *testing.T) {
+	var seen [][]interface{}
+	fc := New(func() int {
+		return 1
+	}).Then(func(n int, s string) {}).Before(func(input []interface{}) {
+		seen = append(seen, input)
+	})
+
+	if _, err := fc.Do(); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(seen, [][]interface{}{nil, {1}}) {
+		t.Fatalf("before hooks should receive the raw arguments by default, got %v", seen)
+	}
+
+	seen = nil
+	if _, err := fc.WithHookTiming(HookAfterAssembly).Do(); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(seen, [][]interface{}{{}, {1, ""}}) {
+		t.Fatalf("before hooks should receive the assembled arguments, got %v", seen)
+	}
+}
+
+func TestInputFunc(t *testing.T) {
+	var (
+		base   = 1
+		evals  int
+		result int
+	)
+	fc := New(func(a, b int) int {
+		return a + b
+	}).InputFunc(func() []interface{} {
+		evals++
+		return []interface{}{base, 10}
+	})
+	if evals != 0 {
+		t.Fatal("input should not be evaluated when building the chain")
+	}
+	base = 5
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 15 || evals != 1 {
+		t.Fatalf("unexpected state: result=%d, evals=%d", result, evals)
+	}
+}
+
+func TestThenAlways(t *testing.T) {
+	errBoom := errors.New("boom")
+	var (
+		seen    error
+		seenArg int
+		skipped bool
+	)
+	fc := New(func() int {
+		return 1
+	}, func(n int) (int, error) {
+		return 0, errBoom
+	}).Then(func(n int) int {
+		skipped = true
+		return n
+	}).ThenAlways(func(err error, n int) int {
+		seen, seenArg = err, n
+		return n * 10
+	})
+	_, results, err := fc.DoBound()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected original error, got %v", err)
+	}
+	if seen != errBoom || seenArg != 1 || skipped {
+		t.Fatalf("unexpected state: seen=%v, seenArg=%d, skipped=%v", seen, seenArg, skipped)
+	}
+	if len(results) != 1 || results[0] != 10 {
+		t.Fatalf("unexpected result: expected [10], got %v", results)
+	}
+
+	// 没有出错时和普通步骤一样执行
+	var result int
+	seen = errBoom
+	if _, err := New(func() int { return 2 }).ThenAlways(func(err error, n int) int {
+		seen = err
+		return n + 1
+	}).Then(func(n int) int { return n * 2 }).Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if seen != nil || result != 6 {
+		t.Fatalf("unexpected state: seen=%v, result=%d", seen, result)
+	}
+
+	// 返回 nil error 清除错误后继续执行后续步骤
+	_, err = New(func() (int, error) {
+		return 3, errBoom
+	}).Then(func(n int) int {
+		skipped = true
+		return n
+	}).ThenAlways(func(err error, n int) (int, error) {
+		return 4, nil
+	}).Then(func(n int) int {
+		return n * 2
+	}).Do(&result)
+	if err != nil || result != 8 {
+		t.Fatalf("error should be cleared: result=%d, err=%v", result, err)
+	}
+
+	// 返回新的错误会替换原来的错误
+	errWrapped := errors.New("wrapped")
+	_, err = New(func() error {
+		return errBoom
+	}).ThenAlways(func(err error) error {
+		return errWrapped
+	}).Do()
+	if !errors.Is(err, errWrapped) || errors.Is(err, errBoom) {
+		t.Fatalf("expected replaced error, got %v", err)
+	}
+}
+
+func TestAfterTimed(t *testing.T) {
+	var (
+		durations []time.Duration
+		outputs   [][]interface{}
+		plain     int
+	)
+	_, err := New(func() int {
+		time.Sleep(20 * time.Millisecond)
+		return 1
+	}, func(n int) error {
+		return errors.New("failed")
+	}).After(func(input, output []interface{}) {
+		plain++
+	}).AfterTimed(func(input, output []interface{}, d time.Duration) {
+		durations = append(durations, d)
+		outputs = append(outputs, output)
+	}).Do()
+	if err == nil {
+		t.Fatal("expected error from the second function")
+	}
+	// 出错的函数也会调用
+	if len(durations) != 2 || plain != 2 {
+		t.Fatalf("unexpected hook calls: timed=%d, plain=%d", len(durations), plain)
+	}
+	if durations[0] < 20*time.Millisecond {
+		t.Fatalf("expected at least 20ms for the first function, got %v", durations[0])
+	}
+	if !reflect.DeepEqual(outputs[0], []interface{}{1}) {
+		t.Fatalf("unexpected output: %v", outputs[0])
+	}
+}
+
+func TestIndexedHooks(t *testing.T) {
+	double := func(n int) int { return n * 2 }
+	var before, after []int
+	var types []reflect.Type
+	failedStep := -1
+	_, err := New(func() int {
+		return 1
+	}, double, double).Delay(0).Then(func(n int) error {
+		return errors.New("failed")
+	}).BeforeIndexed(func(step int, fnType reflect.Type, input []interface{}) {
+		before = append(before, step)
+		types = append(types, fnType)
+	}).AfterIndexed(func(step int, fnType reflect.Type, input, output []interface{}) {
+		after = append(after, step)
+	}).OnErrorIndexed(func(step int, fnType reflect.Type, output []interface{}, err error) {
+		failedStep = step
+	}).Do()
+	if err == nil {
+		t.Fatal("expected error from the last function")
+	}
+	if want := []int{0, 1, 2, 3, 4}; !reflect.DeepEqual(before, want) || !reflect.DeepEqual(after, want) {
+		t.Fatalf("unexpected steps: before=%v, after=%v", before, after)
+	}
+	if failedStep != 4 {
+		t.Fatalf("unexpected failed step: %d", failedStep)
+	}
+	// 相同签名的步骤类型相同，组合子步骤没有函数类型
+	if types[1] != reflect.TypeOf(double) || types[2] != types[1] || types[3] != nil || types[0] == types[1] {
+		t.Fatalf("unexpected function types: %v", types)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	errNotFound := errors.New("not found")
+	lookup := func(key string) (string, error) {
+		if key == "known" {
+			return "value", nil
+		}
+		return "", errNotFound
+	}
+	var hooked bool
+	fc := New(lookup, strings.ToUpper).Recover(func(output []interface{}, err error) ([]interface{}, error) {
+		if errors.Is(err, errNotFound) {
+			return []interface{}{"default"}, nil
+		}
+		return output, err
+	}).OnError(func(args []interface{}, err error) {
+		hooked = true
+	})
+	var result string
+	if _, err := fc.InputFunc(func() []interface{} { return []interface{}{"unknown"} }).Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "DEFAULT" || hooked {
+		t.Fatalf("unexpected state: result=%q, hooked=%v", result, hooked)
+	}
+
+	// 替换错误后由下一个钩子处理，最终未恢复时调用错误钩子
+	errWrapped := errors.New("wrapped")
+	_, err := New(func() error {
+		return errors.New("failed")
+	}).Recover(func(output []interface{}, err error) ([]interface{}, error) {
+		return nil, fmt.Errorf("%w: %v", errWrapped, err)
+	}, func(output []interface{}, err error) ([]interface{}, error) {
+		return nil, err
+	}).OnError(func(args []interface{}, err error) {
+		hooked = true
+	}).Do()
+	if !errors.Is(err, errWrapped) || !hooked {
+		t.Fatalf("unexpected state: err=%v, hooked=%v", err, hooked)
+	}
+}
+
+func TestBeforeMutate(t *testing.T) {
+	var seen []interface{}
+	clamp := func(input []interface{}) []interface{} {
+		if len(input) == 0 {
+			return nil
+		}
+		if n, ok := input[0].(int); ok && n > 10 {
+			input[0] = 10
+			return input
+		}
+		return nil
+	}
+	var result int
+	_, err := New(func() int {
+		return 42
+	}, func(n int) int {
+		return n + 1
+	}).BeforeMutate(clamp).Before(func(input []interface{}) {
+		seen = append(seen, input...)
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 第一个函数没有参数，钩子返回 nil 时参数不变
+	if result != 11 || !reflect.DeepEqual(seen, []interface{}{10}) {
+		t.Fatalf("unexpected state: result=%d, seen=%v", result, seen)
+	}
+
+	// 改变参数数量时报错
+	_, err = New(func() int {
+		return 1
+	}, func(n int) {}).BeforeMutate(func(input []interface{}) []interface{} {
+		if len(input) == 0 {
+			return nil
+		}
+		return append(input, 2)
+	}).Do()
+	if err == nil || !strings.Contains(err.Error(), "from 1 to 2") {
+		t.Fatalf("expected arity error, got %v", err)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	errFirst := errors.New("first")
+	errSecond := errors.New("second")
+	var (
+		hooked []error
+		last   int
+	)
+	result, err := New(func() int {
+		return 1
+	}, func(n int) (int, error) {
+		return 0, errFirst
+	}, func(n int) int {
+		return n + 1
+	}, func(n int) error {
+		return errSecond
+	}, func(n int) int {
+		last = n
+		return n * 10
+	}).ContinueOnError().OnError(func(args []interface{}, err error) {
+		hooked = append(hooked, err)
+	}).Do()
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+	// 出错的步骤之后用上一步成功的结果继续执行
+	if last != 2 || !reflect.DeepEqual(result, []interface{}{20}) {
+		t.Fatalf("unexpected state: last=%d, result=%v", last, result)
+	}
+	if len(hooked) != 2 || hooked[0] != errFirst || hooked[1] != errSecond {
+		t.Fatalf("unexpected error hook calls: %v", hooked)
+	}
+}
+
+func TestPanicErrorStack(t *testing.T) {
+	_, err := New(func() {
+		explode()
+	}).Do()
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expected *PanicError, got %v", err)
+	}
+	if pe.Value() != "boom" {
+		t.Fatalf("unexpected panic value: %v", pe.Value())
+	}
+	// 堆栈包含引发 panic 的函数
+	if !strings.Contains(string(pe.Stack()), "explode") {
+		t.Fatalf("expected the stack to contain the panicking function, got:\n%s", pe.Stack())
+	}
+	if !strings.Contains(err.Error(), "boom") || strings.Contains(err.Error(), "goroutine") {
+		t.Fatalf("unexpected error message: %v", err)
+	}
+}
+
+func explode() {
+	panic("boom")
+}
+
+func TestErrPanic(t *testing.T) {
+	var hooked []bool
+	hook := func(args []interface{}, err error) {
+		hooked = append(hooked, errors.Is(err, ErrPanic))
+	}
+	_, err := New(explode).OnError(hook).Do()
+	if !errors.Is(err, ErrPanic) {
+		t.Fatalf("expected ErrPanic, got %v", err)
+	}
+	_, err = New(func() error {
+		return errors.New("failed")
+	}).OnError(hook).Do()
+	if err == nil || errors.Is(err, ErrPanic) {
+		t.Fatalf("expected a returned error not matching ErrPanic, got %v", err)
+	}
+	if !reflect.DeepEqual(hooked, []bool{true, false}) {
+		t.Fatalf("unexpected error hook calls: %v", hooked)
+	}
+}
+
+func TestArity(t *testing.T) {
+	three := func() (int, string, bool) {
+		return 1, "a", true
+	}
+	one := func(n int) int {
+		return n + 1
+	}
+	// 多余的返回值被丢弃
+	var result int
+	if _, err := New(three, one).Do(&result); err != nil || result != 2 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	_, err := New(three, one).StrictArity().Do()
+	if err == nil || !strings.Contains(err.Error(), "takes 1 arguments, got 3") {
+		t.Fatalf("expected arity error, got %v", err)
+	}
+	// 缺少的参数仍然补零值
+	if _, err := New(func() {}, one).StrictArity().Do(&result); err != nil || result != 1 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+}
+
+func TestArgConversion(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "funchain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var data []byte
+	_, err = New(func() *os.File {
+		return f
+	}, io.ReadAll).Do(&data)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+
+	// 可转换的类型会被转换
+	type celsius float64
+	var c celsius
+	if _, err := New(func() int { return 21 }, func(c celsius) celsius { return c }).Do(&c); err != nil || c != 21 {
+		t.Fatalf("unexpected state: c=%v, err=%v", c, err)
+	}
+
+	// 不兼容的类型返回明确的错误
+	_, err = New(func() *strings.Reader {
+		return strings.NewReader("a")
+	}, func(w io.Writer) {}).Do()
+	if err == nil || !strings.Contains(err.Error(), "cannot use *strings.Reader as io.Writer") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+	// 整数不会被转换为字符串
+	_, err = New(func() int { return 65 }, func(s string) {}).Do()
+	if err == nil || !strings.Contains(err.Error(), "cannot use int as string") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+}
+
+func TestInput(t *testing.T) {
+	add := func(a, b int) int {
+		return a + b
+	}
+	fc := New(add).Input(3, 4)
+	var result int
+	if _, err := fc.Do(&result); err != nil || result != 7 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	// DoWith 替换 Input 设置的参数
+	if _, err := fc.DoWith([]interface{}{10, 20}, &result); err != nil || result != 30 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	// 缺少的参数补零值，类型不符时报错
+	if _, err := fc.DoWith(nil, &result); err != nil || result != 0 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	if _, err := fc.DoWith([]interface{}{"a"}); err == nil {
+		t.Fatal("expected error for a string argument")
+	}
+}
+
+func TestErrStopChain(t *testing.T) {
+	var (
+		third  bool
+		hooked bool
+		result int
+	)
+	_, err := New(func() int {
+		return 1
+	}, func(n int) (int, error) {
+		// 命中缓存，无需继续处理
+		return 0, fmt.Errorf("cache hit: %w", ErrStopChain)
+	}, func(n int) int {
+		third = true
+		return n + 1
+	}).OnError(func(args []interface{}, err error) {
+		hooked = true
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 返回停止的步骤收到的参数
+	if result != 1 || third || hooked {
+		t.Fatalf("unexpected state: result=%d, third=%v, hooked=%v", result, third, hooked)
+	}
+}
+
+func TestExecFunc(t *testing.T) {
+	errFailed := errors.New("failed")
+	// 返回值中去掉 error，保持顺序
+	result, err := execFunc(func(n int) (int, error, string) {
+		return n, nil, "a"
+	}, []interface{}{1}, nil)
+	if err != nil || !reflect.DeepEqual(result, []interface{}{1, "a"}) {
+		t.Fatalf("unexpected outcome: %v, %v", result, err)
+	}
+	result, err = execFunc(func() (int, error) {
+		return 2, errFailed
+	}, nil, nil)
+	if err != errFailed || !reflect.DeepEqual(result, []interface{}{2}) {
+		t.Fatalf("unexpected outcome: %v, %v", result, err)
+	}
+	result, err = execFunc(func(xs ...int) int {
+		return len(xs)
+	}, []interface{}{1, 2, 3}, nil)
+	if err != nil || !reflect.DeepEqual(result, []interface{}{3}) {
+		t.Fatalf("unexpected outcome: %v, %v", result, err)
+	}
+	if _, err = execFunc(explode, nil, nil); !errors.Is(err, ErrPanic) {
+		t.Fatalf("expected a panic error, got %v", err)
+	}
+	if _, err = execFunc(func() (error, error) { return nil, nil }, nil, nil); err == nil || err.Error() != "more than one error" {
+		t.Fatalf("expected multiple errors to be rejected, got %v", err)
+	}
+	if _, err = execFunc(42, nil, nil); err == nil {
+		t.Fatal("expected error for a non-function")
+	}
+}
+
+func TestBindMismatch(t *testing.T) {
+	var (
+		n int
+		s string
+		f float64
+	)
+	bound, _, err := New(func() (int, int, error) {
+		return 1, 2, nil
+	}).DoBound(&n, &s)
+	if err == nil || err.Error() != "cannot assign result 1 (int) to *string" {
+		t.Fatalf("expected binding error, got %v", err)
+	}
+	if bound != 1 || n != 1 {
+		t.Fatalf("unexpected state: bound=%d, n=%d", bound, n)
+	}
+	// 可转换的结果会被转换，nil 结果设置为零值
+	s = "x"
+	if _, err := New(func() (int, interface{}) { return 2, nil }).Do(&f, &s); err != nil || f != 2 || s != "" {
+		t.Fatalf("unexpected state: f=%v, s=%q, err=%v", f, s, err)
+	}
+}
+
+func TestStepError(t *testing.T) {
+	errFailed := errors.New("failed")
+	parse := func(s string) (int, error) {
+		return 0, errFailed
+	}
+	_, err := New(func() string {
+		return "a"
+	}, parse).Do()
+	var se *StepError
+	if !errors.As(err, &se) || se.Index() != 1 || se.FuncType() != reflect.TypeOf(parse) {
+		t.Fatalf("expected *StepError of step 1, got %#v", err)
+	}
+	if !errors.Is(err, errFailed) || err.Error() != "failed" {
+		t.Fatalf("StepError should wrap the original error, got %v", err)
+	}
+
+	// panic 的步骤
+	_, err = New(func() int { return 1 }, double, explode).Do()
+	var pe *PanicError
+	if !errors.As(err, &se) || se.Index() != 2 || !errors.As(err, &pe) {
+		t.Fatalf("expected *StepError of step 2 wrapping a panic, got %v", err)
+	}
+
+	// RawErrors 返回原始错误
+	if _, err = New(parse).RawErrors().Do(); err != errFailed {
+		t.Fatalf("expected the raw error, got %#v", err)
+	}
+}
+
+func TestDoRepeated(t *testing.T) {
+	fc := New(func() (int, string) {
+		return 3, "a"
+	}, func(n int, s string) []string {
+		return []string{strings.Repeat(s, n), s}
+	}).Distinct().Map(strings.ToUpper)
+	var first []interface{}
+	for i := 0; i < 3; i++ {
+		var out []string
+		result, err := fc.Do(&out)
+		if err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if !reflect.DeepEqual(out, []string{"AAA", "A"}) {
+			t.Fatalf("run %d: unexpected result: %v", i, out)
+		}
+		if i == 0 {
+			first = result
+		} else if !reflect.DeepEqual(result, first) {
+			t.Fatalf("run %d: result %v differs from first run %v", i, result, first)
+		}
+	}
+}
+
+func TestConcurrentThenAndDo(t *testing.T) {
+	fc := New(func() int {
+		return 1
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				fc.Then(func(n int) int { return n + 1 }).Before(func(args []interface{}) {})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				// 运行中的函数链使用开始时的快照，结果只取决于当时的步骤数
+				if _, err := fc.Do(); err != nil {
+					t.Error("Chain execution error:", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	var result int
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 201 {
+		t.Fatalf("unexpected result: expected 201, got %d", result)
+	}
+}
+
+func TestConcurrentConfigureAndCombinator(t *testing.T) {
+	fc := New(func() []int {
+		return []int{1, 2, 3}
+	}).FlatMap(func(n int) []int {
+		return []int{n, n}
+	}).Map(func(n int) int {
+		return n * 2
+	})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			fc.WithFanOutLimit(100).WithZeroProvider(nil)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			// 组合步骤读取的是快照中的设置
+			if _, err := fc.Do(); err != nil {
+				t.Error("Chain execution error:", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestDoChecked(t *testing.T) {
+	var ran bool
+	fc := New(func() (int, string) {
+		ran = true
+		return 1, "a"
+	})
+	var (
+		n int
+		s string
+		p *int
+	)
+	for _, out := range [][]interface{}{{&n, s}, {&n, p}, {&n, nil}} {
+		_, err := fc.DoChecked(out...)
+		if err == nil || !strings.Contains(err.Error(), "out[1]") {
+			t.Fatalf("expected error for out[1], got %v", err)
+		}
+	}
+	if ran {
+		t.Fatal("chain should not run with invalid out arguments")
+	}
+	if _, err := fc.DoChecked(&n, &s); err != nil || n != 1 || s != "a" {
+		t.Fatalf("unexpected state: n=%d, s=%q, err=%v", n, s, err)
+	}
+}
+
+func TestMustDo(t *testing.T) {
+	var result int
+	if r := New(func() int { return 1 }).MustDo(&result); len(r) != 1 || result != 1 {
+		t.Fatalf("unexpected result: %v", r)
+	}
+
+	errFailed := errors.New("failed")
+	var deferred, hooked bool
+	fc := New(func() error {
+		return errFailed
+	}).OnError(func(args []interface{}, err error) {
+		hooked = true
+	}).Defer(func() {
+		deferred = true
+	})
+	defer func() {
+		err, ok := recover().(error)
+		if !ok || !errors.Is(err, errFailed) {
+			t.Fatalf("expected the chain error as panic value, got %v", err)
+		}
+		if !deferred || !hooked {
+			t.Fatalf("unexpected state: deferred=%v, hooked=%v", deferred, hooked)
+		}
+	}()
+	fc.MustDo()
+	t.Fatal("expected MustDo to panic")
+}
+
+func TestThenDefaults(t *testing.T) {
+	fetch := func(url string, timeout time.Duration, retries int) string {
+		return fmt.Sprintf("%s %s %d", url, timeout, retries)
+	}
+	var result string
+	_, err := New(func() string {
+		return "example.com"
+	}).ThenDefaults(fetch, "unused", 30*time.Second, "not an int").Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 已有的参数不会被默认值覆盖，类型不匹配的默认值使用零值
+	if result != "example.com 30s 0" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+
+	_, err = New(func() (string, time.Duration) {
+		return "example.com", time.Second
+	}).ThenDefaults(fetch, "", 30*time.Second, 3).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "example.com 1s 3" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		ran     []int
+		hookErr error
+	)
+	fc := New(func() int {
+		ran = append(ran, 1)
+		return 1
+	}, func(n int) int {
+		ran = append(ran, 2)
+		// 第二步取消 context，第三步不再执行
+		cancel()
+		return n + 1
+	}, func(n int) int {
+		ran = append(ran, 3)
+		return n + 1
+	}).OnError(func(args []interface{}, err error) {
+		hookErr = err
+	})
+	_, err := fc.DoContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !errors.Is(hookErr, context.Canceled) {
+		t.Fatalf("error hook should receive the context error, got %v", hookErr)
+	}
+	if !reflect.DeepEqual(ran, []int{1, 2}) {
+		t.Fatalf("unexpected executed steps: %v", ran)
+	}
+
+	// 第一个参数是 context.Context 的函数自动注入 ctx
+	type key struct{}
+	ctx = context.WithValue(context.Background(), key{}, "v")
+	var result string
+	_, err = New(func() int {
+		return 2
+	}, func(ctx context.Context, n int) string {
+		return strings.Repeat(ctx.Value(key{}).(string), n)
+	}).DoContext(ctx, &result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "vv" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+
+	// Do 使用 context.Background()
+	var hasCtx bool
+	_, err = New(func(ctx context.Context) bool {
+		return ctx != nil
+	}).Do(&hasCtx)
+	if err != nil || !hasCtx {
+		t.Fatalf("function should receive a context: %v, err=%v", hasCtx, err)
+	}
+}
+
+func TestErrorHookAlias(t *testing.T) {
+	var calls []string
+	_, err := New(func() error {
+		return errors.New("failed")
+	}).OnError(func(args []interface{}, err error) {
+		calls = append(calls, "OnError")
+	}).ErrorHook(func(args []interface{}, err error) {
+		calls = append(calls, "ErrorHook")
+	}).Do()
+	if err == nil {
+		t.Fatal("expected chain error")
+	}
+	// 两种方式注册的钩子按注册顺序执行
+	if !reflect.DeepEqual(calls, []string{"OnError", "ErrorHook"}) {
+		t.Fatalf("unexpected hook calls: %v", calls)
+	}
+}
+
+func TestNilValues(t *testing.T) {
+	var (
+		gotFile *os.File
+		called  bool
+	)
+	// 中间函数返回 nil 指针和 nil 接口，链条继续执行
+	_, err := New(func() (*os.File, error) {
+		return nil, nil
+	}, func(f *os.File) (interface{}, io.Reader) {
+		gotFile = f
+		called = true
+		return nil, nil
+	}, func(v interface{}, r io.Reader) (*os.File, error, int) {
+		return nil, nil, 1
+	}).Do()
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !called || gotFile != nil {
+		t.Fatalf("unexpected state: called=%v, file=%v", called, gotFile)
+	}
+
+	// 绑定 nil 结果时设置零值
+	var (
+		v  interface{} = "old"
+		r  io.Reader   = strings.NewReader("old")
+		fp             = &os.File{}
+	)
+	_, err = New(func() (interface{}, io.Reader, *os.File) {
+		return nil, nil, nil
+	}).Do(&v, &r, &fp)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if v != nil || r != nil || fp != nil {
+		t.Fatalf("nil results should be bound as zero values: %v, %v, %v", v, r, fp)
+	}
+}
+
+func TestVariadic(t *testing.T) {
+	sum := func(xs ...int) int {
+		total := 0
+		for _, x := range xs {
+			total += x
+		}
+		return total
+	}
+	cases := []struct {
+		upstream interface{}
+		expected int
+	}{
+		{func() (int, int, int) { return 1, 2, 3 }, 6},
+		{func() int { return 5 }, 5},
+		// 没有可变参数时传入空切片
+		{func() {}, 0},
+	}
+	for i, c := range cases {
+		var result int
+		if _, err := New(c.upstream, sum).Do(&result); err != nil {
+			t.Fatalf("case %d: Chain execution error: %v", i, err)
+		}
+		if result != c.expected {
+			t.Fatalf("case %d: unexpected result: expected %d, got %d", i, c.expected, result)
+		}
+	}
+
+	var joined string
+	_, err := New(func() (string, string, string) {
+		return "-", "a", "b"
+	}, func(sep string, parts ...string) string {
+		return strings.Join(parts, sep)
+	}).Do(&joined)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if joined != "a-b" {
+		t.Fatalf("unexpected result: %q", joined)
+	}
+}
+
+func TestClone(t *testing.T) {
+	var hooked []string
+	base := New(func() int {
+		return 1
+	}).After(func(input, output []interface{}) {
+		hooked = append(hooked, fmt.Sprint(output...))
+	})
+	a := base.Clone().Then(func(n int) int { return n + 1 })
+	b := base.Clone().Then(strconv.Itoa, func(s string) string { return s + "!" })
+
+	var ra int
+	if _, err := a.Do(&ra); err != nil || ra != 2 {
+		t.Fatalf("unexpected state: result=%d, err=%v", ra, err)
+	}
+	var rb string
+	if _, err := b.Do(&rb); err != nil || rb != "1!" {
+		t.Fatalf("unexpected state: result=%q, err=%v", rb, err)
+	}
+	// 原链不受影响
+	var r int
+	if _, err := base.Do(&r); err != nil || r != 1 {
+		t.Fatalf("unexpected state: result=%d, err=%v", r, err)
+	}
+	if len(a.steps) != 2 || len(b.steps) != 3 || len(base.steps) != 1 {
+		t.Fatalf("unexpected steps: a=%d, b=%d, base=%d", len(a.steps), len(b.steps), len(base.steps))
+	}
+	if want := []string{"1", "2", "1", "1", "1!", "1"}; !reflect.DeepEqual(hooked, want) {
+		t.Fatalf("unexpected hook calls: %v", hooked)
+	}
+}
+
+func TestCloneCombinatorSettings(t *testing.T) {
+	dup := func(n int) []int {
+		return []int{n, n}
+	}
+	base := New(func() []int {
+		return []int{1, 2, 3}
+	}).FlatMap(dup)
+	// 克隆后修改的设置对克隆得到的组合步骤生效
+	_, err := base.Clone().WithFanOutLimit(2).Do()
+	if !errors.Is(err, ErrFanOutLimit) {
+		t.Fatalf("expected ErrFanOutLimit from the clone, got %v", err)
+	}
+	var result []int
+	if _, err = base.Do(&result); err != nil || len(result) != 6 {
+		t.Fatalf("unexpected state of the original: result=%v, err=%v", result, err)
+	}
+	// Append 的步骤使用目标链的设置
+	_, err = New().WithFanOutLimit(2).Append(base).Do()
+	if !errors.Is(err, ErrFanOutLimit) {
+		t.Fatalf("expected ErrFanOutLimit from the appended steps, got %v", err)
+	}
+}
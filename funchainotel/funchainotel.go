@@ -0,0 +1,64 @@
+// Package funchainotel propagates OpenTelemetry baggage across the steps of funchain chains.
+//
+// Baggage is carried by the context of a run: a step added with SetBaggage or SetBaggageFunc adds a member
+// to it, and every step after it, as well as spans started from that context and the traces exported from
+// them, sees the member. Unlike a value scoped to a single step, baggage set by a step stays in the context
+// for the rest of the run, including nested chains, but not across the branches of combinators running
+// concurrently.
+//
+// It lives in its own module so that users of funchain who don't need it aren't forced to depend on
+// OpenTelemetry.
+package funchainotel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jiazhoulvke/funchain"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// baggageStep is a funchain.ContextStep adding a baggage member to the context of the run.
+type baggageStep struct {
+	key   string
+	value func(args []interface{}) string
+}
+
+// SetBaggage returns a step adding the member key=value to the baggage of the run,
+// the arguments are passed on unchanged.
+func SetBaggage(key, value string) funchain.Step {
+	return SetBaggageFunc(key, func([]interface{}) string {
+		return value
+	})
+}
+
+// SetBaggageFunc returns a step adding a member to the baggage of the run whose value is computed from
+// the arguments the step receives, e.g. a user ID produced by an earlier step.
+// The arguments are passed on unchanged. The step fails if key or the value are not valid baggage.
+func SetBaggageFunc(key string, value func(args []interface{}) string) funchain.Step {
+	return &baggageStep{key: key, value: value}
+}
+
+// Name returns the name of the step.
+func (s *baggageStep) Name() string {
+	return "SetBaggage(" + s.key + ")"
+}
+
+// Exec passes args on unchanged, the baggage is only set by ExecContext.
+func (s *baggageStep) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	_, args, err := s.ExecContext(ctx, args)
+	return args, err
+}
+
+// ExecContext returns ctx with the member added to its baggage.
+func (s *baggageStep) ExecContext(ctx context.Context, args []interface{}) (context.Context, []interface{}, error) {
+	member, err := baggage.NewMemberRaw(s.key, s.value(args))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid baggage member %q: %w", s.key, err)
+	}
+	b, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot set baggage member %q: %w", s.key, err)
+	}
+	return baggage.ContextWithBaggage(ctx, b), args, nil
+}
@@ -0,0 +1,51 @@
+package funchainotel
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jiazhoulvke/funchain"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// baggageReader 返回 context 中指定的 baggage 值
+type baggageReader string
+
+func (r baggageReader) Name() string {
+	return "read"
+}
+
+func (r baggageReader) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	return []interface{}{baggage.FromContext(ctx).Member(string(r)).Value()}, nil
+}
+
+func TestSetBaggage(t *testing.T) {
+	var tenant, user string
+	_, err := funchain.New(func() string {
+		return "u42"
+	}).Then(
+		SetBaggage("tenant", "acme"),
+		SetBaggageFunc("user", func(args []interface{}) string {
+			return args[0].(string)
+		}),
+		func(id string) string {
+			return id + "!"
+		},
+		baggageReader("user"),
+	).Do(&user)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if user != "u42" {
+		t.Fatalf("unexpected baggage value: %q", user)
+	}
+
+	_, err = funchain.New(SetBaggage("tenant", "acme"), baggageReader("tenant")).Do(&tenant)
+	if err != nil || tenant != "acme" {
+		t.Fatalf("unexpected state: tenant=%q, err=%v", tenant, err)
+	}
+
+	if _, err := funchain.New(SetBaggage("bad key", "v")).Do(); err == nil {
+		t.Fatal("expected error for invalid baggage key")
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/jiazhoulvke/funchain/funchainotel
+
+go 1.21
+
+require (
+	github.com/jiazhoulvke/funchain v0.0.0
+	go.opentelemetry.io/otel v1.28.0
+)
+
+require golang.org/x/sync v0.10.0 // indirect
+
+replace github.com/jiazhoulvke/funchain => ../
@@ -0,0 +1,119 @@
+// Package funchainprom exports the execution metrics of funchain chains to Prometheus.
+//
+// It lives in its own module so that users of funchain who don't need it aren't forced to depend on
+// the Prometheus client library.
+package funchainprom
+
+import (
+	"errors"
+
+	"github.com/jiazhoulvke/funchain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a funchain.EventSink updating Prometheus metrics, labeled by chain and step name:
+//
+//	<namespace>_chain_runs_total{chain}
+//	<namespace>_chain_run_duration_seconds{chain}
+//	<namespace>_chain_step_duration_seconds{chain, step}
+//	<namespace>_chain_errors_total{chain, step}
+//	<namespace>_chain_panics_total{chain, step}
+type Collector struct {
+	runs         *prometheus.CounterVec
+	runDuration  *prometheus.HistogramVec
+	stepDuration *prometheus.HistogramVec
+	errors       *prometheus.CounterVec
+	panics       *prometheus.CounterVec
+}
+
+// NewCollector creates a Collector and registers its metrics with reg.
+// If the metrics are already registered, e.g. by the Collector of another chain, the existing ones are reused.
+func NewCollector(reg prometheus.Registerer, namespace string) (*Collector, error) {
+	c := &Collector{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chain_runs_total",
+			Help:      "Number of chain runs.",
+		}, []string{"chain"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "chain_run_duration_seconds",
+			Help:      "Duration of chain runs.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"chain"}),
+		stepDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "chain_step_duration_seconds",
+			Help:      "Duration of chain steps.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"chain", "step"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chain_errors_total",
+			Help:      "Number of failed chain steps.",
+		}, []string{"chain", "step"}),
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "chain_panics_total",
+			Help:      "Number of panicked chain steps.",
+		}, []string{"chain", "step"}),
+	}
+	var err error
+	if c.runs, err = register(reg, c.runs); err != nil {
+		return nil, err
+	}
+	if c.runDuration, err = register(reg, c.runDuration); err != nil {
+		return nil, err
+	}
+	if c.stepDuration, err = register(reg, c.stepDuration); err != nil {
+		return nil, err
+	}
+	if c.errors, err = register(reg, c.errors); err != nil {
+		return nil, err
+	}
+	if c.panics, err = register(reg, c.panics); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// register registers collector with reg, or returns the collector already registered in its place.
+func register[T prometheus.Collector](reg prometheus.Registerer, collector T) (T, error) {
+	if err := reg.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if errors.As(err, &are) {
+			if existing, ok := are.ExistingCollector.(T); ok {
+				return existing, nil
+			}
+		}
+		return collector, err
+	}
+	return collector, nil
+}
+
+// Handle updates the metrics with a chain event.
+func (c *Collector) Handle(e funchain.Event) {
+	switch e.Type {
+	case funchain.EventStepEnd:
+		c.stepDuration.WithLabelValues(e.Chain, e.StepName).Observe(e.Duration.Seconds())
+		if e.Err != nil {
+			c.errors.WithLabelValues(e.Chain, e.StepName).Inc()
+			if e.Panicked() {
+				c.panics.WithLabelValues(e.Chain, e.StepName).Inc()
+			}
+		}
+	case funchain.EventChainEnd:
+		c.runs.WithLabelValues(e.Chain).Inc()
+		c.runDuration.WithLabelValues(e.Chain).Observe(e.Duration.Seconds())
+	}
+}
+
+// WithPrometheus registers the metrics with reg and makes fc update them on every run.
+// Set the chain name with WithName to tell the chains apart.
+func WithPrometheus(fc *funchain.FunChain, reg prometheus.Registerer, namespace string) (*funchain.FunChain, error) {
+	c, err := NewCollector(reg, namespace)
+	if err != nil {
+		return fc, err
+	}
+	return fc.WithEventSink(c), nil
+}
@@ -0,0 +1,56 @@
+package funchainprom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jiazhoulvke/funchain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func parse(s string) (int, error) {
+	if s == "" {
+		return 0, errors.New("empty")
+	}
+	return len(s), nil
+}
+
+func TestWithPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	fc, err := WithPrometheus(funchain.New(func(s string) string {
+		return s
+	}, parse).WithName("parse"), reg, "test")
+	if err != nil {
+		t.Fatal("WithPrometheus error:", err)
+	}
+	_, _ = fc.Do()
+	// 同一注册表上的第二个函数链复用已注册的指标
+	other, err := WithPrometheus(funchain.New(func() {
+		panic("boom")
+	}).WithName("panic"), reg, "test")
+	if err != nil {
+		t.Fatal("WithPrometheus error:", err)
+	}
+	_, _ = other.Do()
+
+	expected := `
+# HELP test_chain_runs_total Number of chain runs.
+# TYPE test_chain_runs_total counter
+test_chain_runs_total{chain="panic"} 1
+test_chain_runs_total{chain="parse"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), "test_chain_runs_total"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(reg, "test_chain_errors_total"); n != 2 {
+		t.Fatalf("expected errors of 2 steps, got %d", n)
+	}
+	if n := testutil.CollectAndCount(reg, "test_chain_panics_total"); n != 1 {
+		t.Fatalf("expected panics of 1 step, got %d", n)
+	}
+	if n := testutil.CollectAndCount(reg, "test_chain_step_duration_seconds"); n != 3 {
+		t.Fatalf("expected durations of 3 steps, got %d", n)
+	}
+}
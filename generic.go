@@ -0,0 +1,160 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DoNReduce executes the chain n times and folds the result of every run into an accumulator,
+// so that the results of many runs can be aggregated without retaining all of them.
+// n: number of runs.
+// reducer: called with the current accumulator and the result of one run, returns the new accumulator.
+// init: initial accumulator value.
+// It stops at the first run that fails and returns the accumulator folded so far together with the error,
+// unless the chain is set to ContinueOnError: then the failed runs are skipped, the successful ones are
+// folded, and the errors of all failed runs are returned joined with errors.Join.
+func DoNReduce[T any](fc *FunChain, n int, reducer func(acc T, result []interface{}) T, init T) (T, error) {
+	fc.mu.RLock()
+	continueOnError := fc.continueOnError
+	fc.mu.RUnlock()
+	var (
+		acc    = init
+		failed []error
+	)
+	for i := 0; i < n; i++ {
+		result, err := fc.Do()
+		if err != nil {
+			if !continueOnError {
+				return acc, err
+			}
+			failed = append(failed, err)
+			continue
+		}
+		acc = reducer(acc, result)
+	}
+	return acc, errors.Join(failed...)
+}
+
+// MapChain runs fc once per element of items, with the element as the argument of the first function,
+// and collects the first result of every run into a []R in the order of items.
+// It stops at the first run that fails and returns its error prefixed with the index of the element,
+// the original error can still be inspected with errors.Is and errors.As.
+func MapChain[T, R any](items []T, fc *FunChain) ([]R, error) {
+	return MapChainConcurrent[T, R](1, items, fc)
+}
+
+// MapChainConcurrent is like MapChain but runs fc on up to workers elements at the same time, the order
+// of the results still follows the order of items.
+// Once a run fails no new element is started, the runs in flight are waited for, and the error of the
+// first failed element is returned.
+func MapChainConcurrent[T, R any](workers int, items []T, fc *FunChain) ([]R, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		results = make([]R, len(items))
+		errs    = make([]error, len(items))
+		mu      sync.Mutex
+		failed  bool
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, workers)
+	)
+	for i := range items {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, result, err := fc.doSeeded(context.Background(), []interface{}{items[i]}, nil)
+			if err == nil {
+				results[i], err = resultAs[R](result, 0)
+			}
+			if err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				errs[i] = fmt.Errorf("item %d: %w", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// As1 asserts the first value of the results returned by Do to type A.
+func As1[A any](result []interface{}) (A, error) {
+	return resultAs[A](result, 0)
+}
+
+// As2 asserts the first two values of the results returned by Do to types A and B.
+// An error naming the offending index is returned if a value is missing or has another type.
+func As2[A, B any](result []interface{}) (a A, b B, err error) {
+	if a, err = resultAs[A](result, 0); err != nil {
+		return
+	}
+	b, err = resultAs[B](result, 1)
+	return
+}
+
+// As3 asserts the first three values of the results returned by Do to types A, B and C.
+func As3[A, B, C any](result []interface{}) (a A, b B, c C, err error) {
+	if a, b, err = As2[A, B](result); err != nil {
+		return
+	}
+	c, err = resultAs[C](result, 2)
+	return
+}
+
+// As4 asserts the first four values of the results returned by Do to types A, B, C and D.
+func As4[A, B, C, D any](result []interface{}) (a A, b B, c C, d D, err error) {
+	if a, b, c, err = As3[A, B, C](result); err != nil {
+		return
+	}
+	d, err = resultAs[D](result, 3)
+	return
+}
+
+// resultAs asserts result[i] to type T, a nil value yields the zero value of T.
+func resultAs[T any](result []interface{}, i int) (T, error) {
+	var zero T
+	if i >= len(result) {
+		return zero, fmt.Errorf("result %d is missing, got %d results", i, len(result))
+	}
+	if result[i] == nil {
+		return zero, nil
+	}
+	v, ok := result[i].(T)
+	if !ok {
+		return zero, fmt.Errorf("result %d: expected %s, got %T", i, reflect.TypeOf(&zero).Elem(), result[i])
+	}
+	return v, nil
+}
+
+// OnErrorAs adds an error hook that only fires when the error matches T according to errors.As,
+// and passes it the typed error, e.g. OnErrorAs(fc, func(err *os.PathError) { ... }).
+// Several typed handlers can be added, all the matching ones fire.
+func OnErrorAs[T error](fc *FunChain, handler func(T)) *FunChain {
+	return fc.OnError(func(output []interface{}, err error) {
+		var target T
+		if errors.As(err, &target) {
+			handler(target)
+		}
+	})
+}
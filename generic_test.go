@@ -0,0 +1,161 @@
+package funchain
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDoNReduce(t *testing.T) {
+	n := 0
+	fc := New(func() int {
+		n++
+		return n
+	})
+	sum, err := DoNReduce(fc, 4, func(acc int, result []interface{}) int {
+		return acc + result[0].(int)
+	}, 0)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if sum != 1+2+3+4 {
+		t.Fatalf("unexpected sum: expected 10, got %d", sum)
+	}
+
+	// 第三次执行失败时应立即停止
+	runs := 0
+	fc = New(func() (int, error) {
+		runs++
+		if runs == 3 {
+			return 0, errors.New("run failed")
+		}
+		return runs, nil
+	})
+	sum, err = DoNReduce(fc, 5, func(acc int, result []interface{}) int {
+		return acc + result[0].(int)
+	}, 0)
+	if err == nil || err.Error() != "run failed" {
+		t.Fatalf("expected 'run failed' error, got %v", err)
+	}
+	if runs != 3 || sum != 3 {
+		t.Fatalf("unexpected state after failure: runs=%d, sum=%d", runs, sum)
+	}
+	// ContinueOnError 时跳过失败的执行，最后返回所有错误
+	runs = 0
+	fc = New(func() (int, error) {
+		runs++
+		if runs == 1 {
+			return 0, errors.New("run failed")
+		}
+		return runs, nil
+	}).ContinueOnError()
+	sum, err = DoNReduce(fc, 3, func(acc int, result []interface{}) int {
+		return acc + result[0].(int)
+	}, 0)
+	if err == nil || err.Error() != "run failed" {
+		t.Fatalf("expected 'run failed' error, got %v", err)
+	}
+	if runs != 3 || sum != 2+3 {
+		t.Fatalf("unexpected state with ContinueOnError: runs=%d, sum=%d", runs, sum)
+	}
+}
+
+func TestAs(t *testing.T) {
+	result, err := New(func() (string, int, error) {
+		return "foo", 3, nil
+	}).Do()
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	s, n, err := As2[string, int](result)
+	if err != nil {
+		t.Fatal("As2 error:", err)
+	}
+	if s != "foo" || n != 3 {
+		t.Fatalf("unexpected values: s=%s, n=%d", s, n)
+	}
+
+	if _, _, err = As2[string, string](result); err == nil || err.Error() != "result 1: expected string, got int" {
+		t.Fatalf("unexpected type mismatch error: %v", err)
+	}
+	if _, _, _, err = As3[string, int, bool](result); err == nil || err.Error() != "result 2 is missing, got 2 results" {
+		t.Fatalf("unexpected missing result error: %v", err)
+	}
+
+	// nil 值转换为对应类型的零值
+	w, err := As1[io.Writer]([]interface{}{nil})
+	if err != nil || w != nil {
+		t.Fatalf("unexpected result for nil value: %v, %v", w, err)
+	}
+}
+
+func TestOnErrorAs(t *testing.T) {
+	var (
+		pathErr *os.PathError
+		linkErr *os.LinkError
+		anyErr  error
+	)
+	fc := New(func() (*os.File, error) {
+		return os.Open(filepath.Join(t.TempDir(), "missing"))
+	})
+	OnErrorAs(fc, func(err *os.PathError) {
+		pathErr = err
+	})
+	OnErrorAs(fc, func(err *os.LinkError) {
+		linkErr = err
+	})
+	OnErrorAs(fc, func(err error) {
+		anyErr = err
+	})
+	if _, err := fc.Do(); err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if pathErr == nil || pathErr.Op != "open" {
+		t.Fatalf("*os.PathError handler should fire, got %v", pathErr)
+	}
+	if linkErr != nil {
+		t.Fatal("*os.LinkError handler should not fire")
+	}
+	if anyErr == nil {
+		t.Fatal("error handler should fire")
+	}
+}
+
+func TestMapChain(t *testing.T) {
+	errEmpty := errors.New("empty string")
+	fc := New(func(s string) (int, error) {
+		if s == "" {
+			return 0, errEmpty
+		}
+		return len(s), nil
+	})
+	lengths, err := MapChain[string, int]([]string{"a", "bb", "ccc"}, fc)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(lengths, []int{1, 2, 3}) {
+		t.Fatalf("unexpected lengths: %v", lengths)
+	}
+
+	// 某个元素出错时返回带有索引的错误
+	_, err = MapChain[string, int]([]string{"a", "", "ccc"}, fc)
+	if !errors.Is(err, errEmpty) || !strings.HasPrefix(err.Error(), "item 1: ") {
+		t.Fatalf("expected the error of item 1, got %v", err)
+	}
+
+	lengths, err = MapChainConcurrent[string, int](2, []string{"a", "bb", "ccc", "dddd"}, fc)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(lengths, []int{1, 2, 3, 4}) {
+		t.Fatalf("unexpected lengths: %v", lengths)
+	}
+	_, err = MapChainConcurrent[string, int](2, []string{"a", "bb", "", "dddd"}, fc)
+	if !errors.Is(err, errEmpty) || !strings.HasPrefix(err.Error(), "item 2: ") {
+		t.Fatalf("expected the error of item 2, got %v", err)
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/jiazhoulvke/funchain
 
-go 1.16
+go 1.20
+
+require golang.org/x/sync v0.10.0
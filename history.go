@@ -0,0 +1,43 @@
+package funchain
+
+// WithArgHistory makes the chain retain the arguments every function actually receives, after zero values
+// were filled in for missing ones, so that ArgHistory can show how values were carried from step to step.
+// It is disabled by default because of the memory cost of copying the arguments of every step.
+func (fc *FunChain) WithArgHistory() *FunChain {
+	return fc.Configure(WithArgHistory())
+}
+
+// WithArgHistory returns an Option doing the same as FunChain.WithArgHistory.
+func WithArgHistory() Option {
+	return func(fc *FunChain) {
+		fc.argHistory = true
+	}
+}
+
+// ArgHistory returns the arguments received by each step of the root chain during the latest run,
+// entry i holding those of step i. The entries of steps that were not executed, e.g. skipped by When or
+// following a failure, are nil.
+// It returns nil if WithArgHistory is not enabled. When the chain is executed concurrently the
+// history of whichever run finished last is returned.
+// The copies are shallow: values pointing to shared data, such as slices and pointers,
+// reflect later modifications of that data.
+func (fc *FunChain) ArgHistory() [][]interface{} {
+	fc.historyMu.Lock()
+	defer fc.historyMu.Unlock()
+	return fc.history
+}
+
+// recordArgs stores a copy of args as the arguments of step i in history, the steps skipped before it get nil.
+func recordArgs(history [][]interface{}, i int, args []interface{}) [][]interface{} {
+	for len(history) < i {
+		history = append(history, nil)
+	}
+	return append(history[:i], append(make([]interface{}, 0, len(args)), args...))
+}
+
+// setArgHistory stores the history of a run.
+func (fc *FunChain) setArgHistory(history [][]interface{}) {
+	fc.historyMu.Lock()
+	fc.history = history
+	fc.historyMu.Unlock()
+}
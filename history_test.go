@@ -0,0 +1,75 @@
+package funchain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestArgHistory(t *testing.T) {
+	fc := New(func() int {
+		return 1
+	}, func(n int, s string) (int, string) {
+		return n + 1, s + "x"
+	}, func(n int, s string, b bool) int {
+		return n
+	})
+	if _, err := fc.Do(); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if fc.ArgHistory() != nil {
+		t.Fatal("history should be nil when disabled")
+	}
+
+	fc.WithArgHistory()
+	if _, err := fc.Do(); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 记录的是函数实际收到的参数，包括补齐的零值
+	expected := [][]interface{}{
+		{},
+		{1, ""},
+		{2, "x", false},
+	}
+	if !reflect.DeepEqual(fc.ArgHistory(), expected) {
+		t.Fatalf("unexpected history: %v", fc.ArgHistory())
+	}
+}
+
+func TestArgHistorySkippedAndMapped(t *testing.T) {
+	var mapped int
+	fc := New(func() int {
+		return 1
+	}).When(func(input []interface{}) bool {
+		return false
+	}, func(n int) int {
+		return n * 10
+	}).ThenMapArgs(func(args []interface{}) []interface{} {
+		mapped++
+		return []interface{}{args[0].(int) + 1}
+	}, func(n int) int {
+		return n
+	}).Then(func(n int) error {
+		return errors.New("failed")
+	}).Then(func(n int) int {
+		return n
+	}).WithArgHistory().WithHookTiming(HookAfterAssembly).Before(func(input []interface{}) {})
+	if _, err := fc.Do(); err == nil {
+		t.Fatal("expected error")
+	}
+	// 跳过和未执行的步骤对应 nil，其余步骤的下标不变
+	expected := [][]interface{}{
+		{},
+		nil,
+		{2},
+		{2},
+		nil,
+	}
+	if !reflect.DeepEqual(fc.ArgHistory(), expected) {
+		t.Fatalf("unexpected history: %v", fc.ArgHistory())
+	}
+	// 参数映射每次执行只调用一次
+	if mapped != 1 {
+		t.Fatalf("mapping should run once, ran %d times", mapped)
+	}
+}
@@ -0,0 +1,197 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrStopIteration can be returned by the function of Map, MapConcurrent or Each to stop processing
+// the remaining elements cleanly: the step succeeds with what was processed so far instead of failing.
+var ErrStopIteration = errors.New("stop iteration")
+
+// Map adds a step that calls fn on each element of the slice received as first argument.
+// fn: func(T) R or func(T) (R, error).
+// The results are collected into a []R which is passed to the next function. If fn returns
+// ErrStopIteration the remaining elements are skipped and the results of the previous elements are passed on,
+// any other error fails the step.
+func (fc *FunChain) Map(fn interface{}) *FunChain {
+	return fc.MapConcurrent(1, fn)
+}
+
+// MapConcurrent is like Map but calls fn on up to workers elements at the same time, the order of the
+// results still follows the order of the elements.
+// When fn returns an error no new element is started and the elements in flight are waited for.
+// On ErrStopIteration the results of the elements before the stopping one are passed on.
+func (fc *FunChain) MapConcurrent(workers int, fn interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	fc.addStep(&step{fn: fn, kind: "Map", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		return fc.mapSlice(workers, fn, args)
+	}})
+	return fc
+}
+
+func (fc *FunChain) mapSlice(workers int, fn interface{}, args []interface{}) ([]interface{}, error) {
+	items, err := sliceArg(args)
+	if err != nil {
+		return nil, err
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.NumIn() != 1 || fnType.NumOut() == 0 || fnType.Out(0).Implements(errorType) {
+		return nil, fmt.Errorf("Map function must take an element and return a value, got %s", fnType)
+	}
+	var (
+		n       = items.Len()
+		outs    = make([]interface{}, n)
+		errs    = make([]error, n)
+		started = n
+		mu      sync.Mutex
+		failed  bool
+		wg      sync.WaitGroup
+		sem     = make(chan struct{}, workers)
+	)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			// 出错后不再启动新的元素，正在执行的元素继续完成
+			<-sem
+			started = i
+			break
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			out, err := execFunc(fn, []interface{}{items.Index(i).Interface()}, fc.zeroProvider)
+			if err != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				errs[i] = err
+				return
+			}
+			outs[i] = out[0]
+		}(i)
+	}
+	wg.Wait()
+	result := reflect.MakeSlice(reflect.SliceOf(fnType.Out(0)), 0, started)
+	for i := 0; i < started; i++ {
+		if errors.Is(errs[i], ErrStopIteration) {
+			break
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		result = reflect.Append(result, elemValue(outs[i], fnType.Out(0)))
+	}
+	return []interface{}{result.Interface()}, nil
+}
+
+// Each adds a step that calls fn on each element of the slice received as first argument, e.g. for side
+// effects, and passes the arguments on unchanged.
+// fn: func(T) or func(T) error. If fn returns ErrStopIteration the remaining elements are skipped,
+// any other error fails the step.
+func (fc *FunChain) Each(fn interface{}) *FunChain {
+	if !isFunc(fn) {
+		return fc
+	}
+	fc.addStep(&step{fn: fn, kind: "Each", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < items.Len(); i++ {
+			_, err := execFunc(fn, []interface{}{items.Index(i).Interface()}, fc.zeroProvider)
+			if errors.Is(err, ErrStopIteration) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return args, nil
+	}})
+	return fc
+}
+
+// Filter adds a step that keeps the elements of the slice received as first argument for which
+// predicate returns true, and passes them to the next function as a new slice of the same element type.
+// predicate: func(T) bool or func(T) (bool, error), an error fails the step.
+// An empty slice is passed on for an empty input, never nil.
+func (fc *FunChain) Filter(predicate interface{}) *FunChain {
+	if !isFunc(predicate) {
+		return fc
+	}
+	fc.addStep(&step{fn: predicate, kind: "Filter", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		fnType := reflect.TypeOf(predicate)
+		if fnType.NumIn() != 1 || fnType.NumOut() == 0 || fnType.Out(0).Kind() != reflect.Bool {
+			return nil, fmt.Errorf("Filter function must take an element and return a bool, got %s", fnType)
+		}
+		result := reflect.MakeSlice(reflect.SliceOf(items.Type().Elem()), 0, items.Len())
+		for i := 0; i < items.Len(); i++ {
+			out, err := execFunc(predicate, []interface{}{items.Index(i).Interface()}, fc.zeroProvider)
+			if err != nil {
+				return nil, err
+			}
+			if keep, _ := out[0].(bool); keep {
+				result = reflect.Append(result, items.Index(i))
+			}
+		}
+		return []interface{}{result.Interface()}, nil
+	}})
+	return fc
+}
+
+// Reduce adds a step that folds the slice received as first argument into a single value which is
+// passed to the next function.
+// initial: starting value of the accumulator, passed on unchanged if the slice is empty.
+// reducer: func(acc A, item T) A or func(acc A, item T) (A, error), called once per element with the
+// accumulator returned by the previous call. An error fails the step.
+func (fc *FunChain) Reduce(initial interface{}, reducer interface{}) *FunChain {
+	if !isFunc(reducer) {
+		return fc
+	}
+	fc.addStep(&step{fn: reducer, kind: "Reduce", exec: func(fc *FunChain, rs *runState, args []interface{}) ([]interface{}, error) {
+		items, err := sliceArg(args)
+		if err != nil {
+			return nil, err
+		}
+		fnType := reflect.TypeOf(reducer)
+		if fnType.NumIn() != 2 || fnType.NumOut() == 0 || fnType.Out(0).Implements(errorType) {
+			return nil, fmt.Errorf("Reduce function must take an accumulator and an element and return the accumulator, got %s", fnType)
+		}
+		acc := initial
+		for i := 0; i < items.Len(); i++ {
+			out, err := execFunc(reducer, []interface{}{acc, items.Index(i).Interface()}, fc.zeroProvider)
+			if err != nil {
+				return nil, err
+			}
+			acc = out[0]
+		}
+		return []interface{}{acc}, nil
+	}})
+	return fc
+}
+
+// elemValue returns v as a value of type t, nil becomes the zero value of t.
+func elemValue(v interface{}, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
+}
@@ -0,0 +1,171 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMapStopIteration(t *testing.T) {
+	var result []int
+	_, err := New(func() []int {
+		return []int{1, 2, 3, 4, 5}
+	}).Map(func(n int) (int, error) {
+		if n == 4 {
+			return 0, ErrStopIteration
+		}
+		return n * n, nil
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(result, []int{1, 4, 9}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	errBoom := errors.New("boom")
+	_, err = New(func() []int {
+		return []int{1, 2}
+	}).Map(func(n int) (int, error) {
+		return 0, errBoom
+	}).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected genuine error, got %v", err)
+	}
+}
+
+func TestMapConcurrentStopIteration(t *testing.T) {
+	var (
+		calls  int32
+		result []int
+	)
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	_, err := New(func() []int {
+		return items
+	}).MapConcurrent(4, func(n int) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		if n == 10 {
+			return 0, ErrStopIteration
+		}
+		return n + 1, nil
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	expected := make([]int, 10)
+	for i := range expected {
+		expected[i] = i + 1
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+	// 停止后不再启动新元素
+	if c := atomic.LoadInt32(&calls); c >= int32(len(items)) {
+		t.Fatalf("too many elements processed after stop: %d", c)
+	}
+}
+
+func TestEachStopIteration(t *testing.T) {
+	var seen []string
+	var result []string
+	_, err := New(func() []string {
+		return []string{"a", "b", "stop", "c"}
+	}).Each(func(s string) error {
+		if s == "stop" {
+			return ErrStopIteration
+		}
+		seen = append(seen, s)
+		return nil
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(seen, []string{"a", "b"}) || len(result) != 4 {
+		t.Fatalf("unexpected state: seen=%v, result=%v", seen, result)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	sum := func(acc, n int) int {
+		return acc + n
+	}
+	var result string
+	_, err := New(func() []int {
+		return []int{1, 2, 3, 4}
+	}).Reduce(10, sum).Then(func(total int) string {
+		return fmt.Sprintf("total=%d", total)
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "total=20" {
+		t.Fatalf("unexpected result: %s", result)
+	}
+
+	// 空切片时原样传递初始值
+	var total int
+	if _, err = New(func() []int {
+		return nil
+	}).Reduce(10, sum).Do(&total); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if total != 10 {
+		t.Fatalf("expected the initial value, got %d", total)
+	}
+
+	errBoom := errors.New("boom")
+	_, err = New(func() []int {
+		return []int{1, 2}
+	}).Reduce(0, func(acc, n int) (int, error) {
+		return 0, errBoom
+	}).Then(func(int) {
+		t.Fatal("function after a failed Reduce called")
+	}).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected reducer error, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	even := func(n int) bool {
+		return n%2 == 0
+	}
+	var result []int
+	_, err := New(func() []int {
+		return []int{1, 2, 3, 4, 5, 6}
+	}).Filter(even).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(result, []int{2, 4, 6}) {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	// 空输入得到非 nil 的空切片
+	result = nil
+	if _, err = New(func() []int {
+		return []int{}
+	}).Filter(even).Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result == nil || len(result) != 0 {
+		t.Fatalf("expected an empty non-nil slice, got %#v", result)
+	}
+
+	errBoom := errors.New("boom")
+	_, err = New(func() []int {
+		return []int{1, 2}
+	}).Filter(func(n int) (bool, error) {
+		return false, errBoom
+	}).Then(func([]int) {
+		t.Fatal("function after a failed Filter called")
+	}).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected predicate error, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package funchain
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONLSink is an EventSink writing every event as a JSON object on its own line, ready to be ingested
+// by log pipelines:
+//
+//	{"time":"2024-05-01T10:00:00.123Z","type":"step_end","chain":"import","step":1,"step_name":"main.parse","duration_ms":1.5,"error":"bad input"}
+//
+// "error" and "panic" are only present when the step, defer or chain failed, respectively panicked.
+// Writes are synchronized, so a JSONLSink can be shared by chains running concurrently. Each event is
+// written to w as soon as it happens: wrap a slow writer in a bufio.Writer, and flush it yourself, to keep
+// the steps from waiting on it.
+type JSONLSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// jsonlEvent is the JSON encoding of an Event.
+type jsonlEvent struct {
+	Time       string  `json:"time"`
+	Type       string  `json:"type"`
+	Chain      string  `json:"chain,omitempty"`
+	Step       int     `json:"step"`
+	StepName   string  `json:"step_name,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+	Panic      bool    `json:"panic,omitempty"`
+}
+
+// NewJSONLSink creates a JSONLSink writing to w.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	return &JSONLSink{enc: json.NewEncoder(w)}
+}
+
+// Handle writes e as a JSON line, write errors are ignored.
+func (s *JSONLSink) Handle(e Event) {
+	line := jsonlEvent{
+		Time:       time.Now().UTC().Format(time.RFC3339Nano),
+		Type:       e.Type.String(),
+		Chain:      e.Chain,
+		Step:       e.Step,
+		StepName:   e.StepName,
+		DurationMS: float64(e.Duration) / float64(time.Millisecond),
+		Panic:      e.Panicked(),
+	}
+	if e.Err != nil {
+		line.Error = e.Err.Error()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(line)
+}
+
+// WithJSONLSink makes the chain write its lifecycle events to w as JSON lines, see JSONLSink.
+func (fc *FunChain) WithJSONLSink(w io.Writer) *FunChain {
+	return fc.Configure(WithJSONLSink(w))
+}
+
+// WithJSONLSink returns an Option doing the same as FunChain.WithJSONLSink.
+func WithJSONLSink(w io.Writer) Option {
+	return WithEventSink(NewJSONLSink(w))
+}
@@ -0,0 +1,47 @@
+package funchain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONLSink(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := New(func() int {
+		return 1
+	}, func(n int) error {
+		return errors.New("bad input")
+	}).WithName("import").Defer(func() {
+		panic("cleanup failed")
+	}).WithJSONLSink(&buf).Do()
+	if err == nil {
+		t.Fatal("expected chain error")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var types []string
+	var events []map[string]interface{}
+	for _, line := range lines {
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", line, err)
+		}
+		events = append(events, e)
+		types = append(types, e["type"].(string))
+	}
+	expected := "step_start step_end step_start step_end chain_end defer"
+	if strings.Join(types, " ") != expected {
+		t.Fatalf("unexpected event types: %v", types)
+	}
+	if events[3]["error"] != "bad input" || events[3]["step"] != 1.0 || events[3]["chain"] != "import" {
+		t.Fatalf("unexpected step event: %v", events[3])
+	}
+	if _, ok := events[3]["duration_ms"]; !ok {
+		t.Fatalf("missing duration: %v", events[3])
+	}
+	if events[5]["panic"] != true {
+		t.Fatalf("defer panic not reported: %v", events[5])
+	}
+}
@@ -0,0 +1,45 @@
+package funchain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Logger receives a record of every executed step of a chain, see WithLogger.
+type Logger interface {
+	// Step is called after the step at index ran, with its name, its arguments, its results, the
+	// time it took and its error if it failed. input and output are redacted, see RedactArgs.
+	Step(index int, name string, input, output []interface{}, d time.Duration, err error)
+}
+
+// LoggerFunc adapts a function to a Logger.
+type LoggerFunc func(index int, name string, input, output []interface{}, d time.Duration, err error)
+
+// Step calls f.
+func (f LoggerFunc) Step(index int, name string, input, output []interface{}, d time.Duration, err error) {
+	f(index, name, input, output, d, err)
+}
+
+// WithLogger makes the chain report every step it executes to l, so that pipelines are logged the
+// same way without hand-written Before and After hooks. Steps skipped by When or Unless are not reported.
+// l is called synchronously from the goroutine executing the chain, nil disables logging.
+func (fc *FunChain) WithLogger(l Logger) *FunChain {
+	return fc.Configure(WithLogger(l))
+}
+
+// WithLogger returns an Option doing the same as FunChain.WithLogger.
+func WithLogger(l Logger) Option {
+	return func(fc *FunChain) {
+		fc.logger = l
+	}
+}
+
+// logStep reports the step at index i to the logger with recovery protection.
+func (fc *FunChain) logStep(i int, st *step, input, output []interface{}, d time.Duration, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Panic from logger:", r)
+		}
+	}()
+	fc.logger.Step(i, fc.stepName(st), fc.redact(i, input), fc.redact(i, output), d, err)
+}
@@ -0,0 +1,42 @@
+//go:build go1.21
+
+package funchain
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SlogLogger is a Logger writing a record per step to a slog.Logger: at info level for the steps that
+// succeed and at error level for the ones that fail. The arguments and results are only added at debug level.
+type SlogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a SlogLogger writing to l, or to slog.Default() if l is nil.
+// Use l.With to add attributes such as the name of the chain to every record.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &SlogLogger{l: l}
+}
+
+// Step writes the record of a step.
+func (s *SlogLogger) Step(index int, name string, input, output []interface{}, d time.Duration, err error) {
+	attrs := []slog.Attr{
+		slog.Int("step", index),
+		slog.String("name", name),
+		slog.Duration("duration", d),
+	}
+	ctx := context.Background()
+	if s.l.Enabled(ctx, slog.LevelDebug) {
+		attrs = append(attrs, slog.Any("input", input), slog.Any("output", output))
+	}
+	if err != nil {
+		s.l.LogAttrs(ctx, slog.LevelError, "step failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	s.l.LogAttrs(ctx, slog.LevelInfo, "step done", attrs...)
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package funchain
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(slog.NewTextHandler(&buf, nil))
+	_, err := New(func() int {
+		return 1
+	}, func(n int) error {
+		return errors.New("boom")
+	}).WithLogger(NewSlogLogger(l)).Do()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[0], "level=INFO") || !strings.Contains(lines[0], "step=0") {
+		t.Fatalf("unexpected record of the first step: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "level=ERROR") || !strings.Contains(lines[1], "error=boom") {
+		t.Fatalf("unexpected record of the failed step: %s", lines[1])
+	}
+}
@@ -0,0 +1,47 @@
+package funchain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type loggedStep struct {
+	index  int
+	input  []interface{}
+	output []interface{}
+	err    error
+}
+
+func TestWithLogger(t *testing.T) {
+	errBoom := errors.New("boom")
+	var logged []loggedStep
+	_, err := New(func() int {
+		return 1
+	}, func(n int) int {
+		return n + 1
+	}, func(n int) (int, error) {
+		return 0, errBoom
+	}).WithLogger(LoggerFunc(func(index int, name string, input, output []interface{}, d time.Duration, err error) {
+		logged = append(logged, loggedStep{index: index, input: input, output: output, err: err})
+	})).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom error, got %v", err)
+	}
+	// 每个步骤记录一次，只有最后一步带有错误
+	if len(logged) != 3 {
+		t.Fatalf("expected 3 logged steps, got %d", len(logged))
+	}
+	for i, l := range logged {
+		if l.index != i || (l.err != nil) != (i == 2) {
+			t.Fatalf("unexpected record %d: %+v", i, l)
+		}
+	}
+	if !reflect.DeepEqual(logged[1].input, []interface{}{1}) || !reflect.DeepEqual(logged[1].output, []interface{}{2}) {
+		t.Fatalf("unexpected arguments of step 1: %+v", logged[1])
+	}
+	if !errors.Is(logged[2].err, errBoom) {
+		t.Fatalf("unexpected error of step 2: %v", logged[2].err)
+	}
+}
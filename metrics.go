@@ -0,0 +1,35 @@
+package funchain
+
+import "time"
+
+// MetricsSink receives the duration and the error of every executed step of a chain, so that step counts
+// and latencies can be exported to Prometheus, statsd and the like without funchain depending on them.
+// ObserveStep is called synchronously from the goroutine executing the chain, it must be cheap and must not block.
+type MetricsSink interface {
+	ObserveStep(name string, d time.Duration, err error)
+}
+
+// WithMetrics makes the chain report every step it executes to m, failed steps included.
+// It is a shorthand for an EventSink handling EventStepEnd, see WithEventSink for richer events.
+func (fc *FunChain) WithMetrics(m MetricsSink) *FunChain {
+	return fc.Configure(WithMetrics(m))
+}
+
+// WithMetrics returns an Option doing the same as FunChain.WithMetrics.
+func WithMetrics(m MetricsSink) Option {
+	if m == nil {
+		return nil
+	}
+	return WithEventSink(metricsSink{m})
+}
+
+// metricsSink adapts a MetricsSink to an EventSink.
+type metricsSink struct {
+	m MetricsSink
+}
+
+func (s metricsSink) Handle(e Event) {
+	if e.Type == EventStepEnd {
+		s.m.ObserveStep(e.StepName, e.Duration, e.Err)
+	}
+}
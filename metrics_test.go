@@ -0,0 +1,47 @@
+package funchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type observedStep struct {
+	name string
+	d    time.Duration
+	err  error
+}
+
+type fakeMetrics struct {
+	steps []observedStep
+}
+
+func (m *fakeMetrics) ObserveStep(name string, d time.Duration, err error) {
+	m.steps = append(m.steps, observedStep{name: name, d: d, err: err})
+}
+
+func TestWithMetrics(t *testing.T) {
+	errBoom := errors.New("boom")
+	clock := &fakeClock{now: time.Now()}
+	m := &fakeMetrics{}
+	_, err := New().ThenNamed("load", func() int {
+		clock.Advance(time.Second)
+		return 1
+	}).ThenNamed("save", func(n int) error {
+		clock.Advance(2 * time.Second)
+		return errBoom
+	}).WithClock(clock).WithMetrics(m).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom error, got %v", err)
+	}
+	// 失败的步骤同样记录耗时
+	expected := []observedStep{{"load", time.Second, nil}, {"save", 2 * time.Second, errBoom}}
+	if len(m.steps) != len(expected) {
+		t.Fatalf("expected %d observations, got %+v", len(expected), m.steps)
+	}
+	for i, s := range m.steps {
+		if s.name != expected[i].name || s.d != expected[i].d || !errors.Is(s.err, expected[i].err) || (s.err == nil) != (expected[i].err == nil) {
+			t.Fatalf("unexpected observation %d: %+v", i, s)
+		}
+	}
+}
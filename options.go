@@ -0,0 +1,38 @@
+package funchain
+
+// Option configures a chain, see Configure.
+// Every setting method of FunChain such as WithClock or WithMaxDepth has a package level function of
+// the same name returning the equivalent Option, so that settings can be collected, shared between
+// chains and applied at once.
+type Option func(fc *FunChain)
+
+// Configure applies opts to the chain in order, nil options are ignored.
+//
+//	fc := funchain.New(parse, validate, save).Configure(
+//		funchain.WithName("import"),
+//		funchain.WithMaxDepth(4),
+//		funchain.AbortAfter(time.Second),
+//	)
+func (fc *FunChain) Configure(opts ...Option) *FunChain {
+	fc.mu.Lock()
+	defer fc.unlock()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(fc)
+		}
+	}
+	return fc
+}
+
+// NewWith creates a chain from fns like New and applies opts to it, so that a chain and its settings
+// can be set up in one call. Options run after the functions are added.
+//
+//	fc := funchain.NewWith([]interface{}{parse, validate, save},
+//		funchain.StrictArity(),
+//		funchain.ContinueOnError(),
+//	)
+//
+// There is no option for panic stacks, a PanicError always carries the stack of the panic.
+func NewWith(fns []interface{}, opts ...Option) *FunChain {
+	return New(fns...).Configure(opts...)
+}
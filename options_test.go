@@ -0,0 +1,60 @@
+package funchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	var events int
+	common := []Option{
+		WithName("configured"),
+		WithMaxDepth(3),
+		nil,
+		WithEventSink(EventSinkFunc(func(e Event) {
+			if e.Chain == "configured" {
+				events++
+			}
+		})),
+	}
+	fc := New(func() int {
+		return 1
+	}, double).Configure(common...)
+	var result int
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 2 {
+		t.Fatalf("unexpected result: expected 2, got %d", result)
+	}
+	if fc.maxDepth != 3 || events == 0 {
+		t.Fatalf("options not applied: maxDepth=%d, events=%d", fc.maxDepth, events)
+	}
+
+	// 方法和 Option 效果相同
+	if New().WithStreamBuffer(-1).streamBuffer != 0 || New().Configure(WithStreamBuffer(4)).streamBuffer != 4 {
+		t.Fatal("WithStreamBuffer option not applied")
+	}
+}
+
+func TestNewWith(t *testing.T) {
+	result, err := NewWith([]interface{}{
+		func() (int, int) {
+			return 1, 2
+		},
+		func(a int) int {
+			t.Fatal("function with surplus arguments called")
+			return a
+		},
+		func(a, b int) int {
+			return a + b
+		},
+	}, StrictArity(), ContinueOnError()).Do()
+	// StrictArity 使第二个函数失败，ContinueOnError 使第三个函数继续执行
+	if err == nil {
+		t.Fatal("expected an arity error")
+	}
+	if !reflect.DeepEqual(result, []interface{}{3}) {
+		t.Fatalf("unexpected result: expected [3], got %v", result)
+	}
+}
@@ -0,0 +1,229 @@
+package funchain
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// StepInfo describes a step of the function chain.
+type StepInfo struct {
+	// Index is the zero-based position of the step in the chain, or in its combinator for branches.
+	Index int
+	// Name is the name given with ThenNamed, or the name of the function as reported by the runtime, closures
+	// show up as e.g. "pkg.main.func1", unless a name resolver set with WithNameResolver supplies a better one.
+	Name string
+	// Doc is the description supplied with ThenDoc, empty if none.
+	Doc string
+	// Type is the type of the function, nil if the step is a nested chain or a combinator without function.
+	Type reflect.Type
+	// Kind is the name of the combinator that created the step, such as "Race", empty for plain functions.
+	Kind string
+	// Branches describes the functions run by a combinator such as Race.
+	Branches []StepInfo
+}
+
+// Plan returns the description of every step of the chain in execution order.
+func (fc *FunChain) Plan() []StepInfo {
+	snap := fc.snapshot()
+	plan := make([]StepInfo, 0, len(snap.steps))
+	for i, st := range snap.steps {
+		plan = append(plan, snap.stepInfo(i, st))
+	}
+	return plan
+}
+
+func (fc *FunChain) stepInfo(index int, st *step) StepInfo {
+	info := StepInfo{
+		Index: index,
+		Name:  fc.stepName(st),
+		Doc:   st.doc,
+		Kind:  st.kind,
+	}
+	if st.fn != nil {
+		if isFunc(st.fn) {
+			info.Type = reflect.TypeOf(st.fn)
+		}
+	}
+	for i, branch := range st.branches {
+		info.Branches = append(info.Branches, fc.stepInfo(i, branch))
+	}
+	return info
+}
+
+// WithNameResolver sets a function naming the functions of the chain, e.g. from a registry, used wherever
+// names appear: Plan, events, the watchdog, duplicate warnings and panic errors.
+// resolve receives the function (or Step, or nested chain) of a step, when it returns an empty string
+// the name reported by the runtime is used.
+func (fc *FunChain) WithNameResolver(resolve func(fn interface{}) string) *FunChain {
+	return fc.Configure(WithNameResolver(resolve))
+}
+
+// WithNameResolver returns an Option doing the same as FunChain.WithNameResolver.
+func WithNameResolver(resolve func(fn interface{}) string) Option {
+	return func(fc *FunChain) {
+		fc.nameResolver = resolve
+	}
+}
+
+// StepName returns the name of the step at index i as reported by Plan, e.g. to name the steps in indexed
+// hooks, or an empty string if i is out of range.
+func (fc *FunChain) StepName(i int) string {
+	snap := fc.snapshot()
+	if i < 0 || i >= len(snap.steps) {
+		return ""
+	}
+	return snap.stepName(snap.steps[i])
+}
+
+// Describe returns a one-line listing of the steps of the chain, e.g. "1:openFile -> 2:read -> 3:append".
+// Steps are numbered from 1 and named like in Plan, without the package path for functions named by the
+// runtime. Anonymous functions are shown by their type instead, such as "2:func(int) string".
+func (fc *FunChain) Describe() string {
+	snap := fc.snapshot()
+	parts := make([]string, 0, len(snap.steps))
+	for i, st := range snap.steps {
+		parts = append(parts, fmt.Sprintf("%d:%s", i+1, snap.describeStep(st)))
+	}
+	return strings.Join(parts, " -> ")
+}
+
+// describeStep returns the short name of st for Describe.
+func (fc *FunChain) describeStep(st *step) string {
+	name := fc.stepName(st)
+	if name != st.name() || !isFunc(st.fn) {
+		// 由 ThenNamed 或名称解析器给出的名称，以及 Step、子链和组合子的名称原样使用
+		return name
+	}
+	name = name[strings.LastIndex(name, "/")+1:]
+	if isAnonymous(name) || name == "" {
+		return reflect.TypeOf(st.fn).String()
+	}
+	// 去掉包名
+	return name[strings.Index(name, ".")+1:]
+}
+
+// stepName returns the name of st, as given by ThenNamed or by the name resolver if set.
+func (fc *FunChain) stepName(st *step) string {
+	return stepNameWith(fc.nameResolver, st)
+}
+
+// stepNameWith returns the name of st, as given by ThenNamed or by resolver if not nil.
+func stepNameWith(resolver func(fn interface{}) string, st *step) string {
+	if st.label != "" {
+		return st.label
+	}
+	if resolver != nil && st.fn != nil {
+		if name := resolver(st.fn); name != "" {
+			return name
+		}
+	}
+	return st.name()
+}
+
+// name returns the name of the step, i.e. the name of its function or of its combinator.
+func (st *step) name() string {
+	if st.fn == nil {
+		return st.kind
+	}
+	return funcName(st.fn)
+}
+
+// funcName returns the name of a function, the name of a Step, or "FunChain" for a nested chain.
+func funcName(fn interface{}) string {
+	if _, ok := fn.(*FunChain); ok {
+		return "FunChain"
+	}
+	if s, ok := fn.(Step); ok {
+		return s.Name()
+	}
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// DOT returns a Graphviz DOT graph of the chain: one node per step and edges labeled with the types
+// carried from a step to the next. The branches of combinators such as Race are drawn as clusters.
+func (fc *FunChain) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph funchain {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	// prev 为上一步的出口节点及其携带的类型
+	var prev []dotNode
+	for _, info := range fc.Plan() {
+		var cur []dotNode
+		if len(info.Branches) == 0 {
+			id := fmt.Sprintf("n%d", info.Index)
+			fmt.Fprintf(&b, "\t%s [label=%s];\n", id, strconv.Quote(dotLabel(info)))
+			cur = append(cur, dotNode{id: id, types: outTypes(info.Type)})
+		} else {
+			fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n\t\tlabel=%s;\n", info.Index, strconv.Quote(info.Kind))
+			for _, branch := range info.Branches {
+				id := fmt.Sprintf("n%d_%d", info.Index, branch.Index)
+				fmt.Fprintf(&b, "\t\t%s [label=%s];\n", id, strconv.Quote(dotLabel(branch)))
+				cur = append(cur, dotNode{id: id, types: outTypes(branch.Type)})
+			}
+			b.WriteString("\t}\n")
+		}
+		for _, from := range prev {
+			for _, to := range cur {
+				fmt.Fprintf(&b, "\t%s -> %s [label=%s];\n", from.id, to.id, strconv.Quote(from.types))
+			}
+		}
+		prev = cur
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotNode is a node of the DOT graph with the types it passes to the next step.
+type dotNode struct {
+	id    string
+	types string
+}
+
+// dotLabel returns the label of a step: its description, its function name, or a synthesized
+// label made of the index and function type for anonymous functions.
+func dotLabel(info StepInfo) string {
+	if info.Doc != "" {
+		return info.Doc
+	}
+	name := info.Name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" || isAnonymous(name) {
+		if info.Type != nil {
+			return fmt.Sprintf("step %d: %s", info.Index, info.Type)
+		}
+		return fmt.Sprintf("step %d", info.Index)
+	}
+	return name
+}
+
+// isAnonymous reports whether name is the runtime name of a closure, e.g. "pkg.main.func1".
+func isAnonymous(name string) bool {
+	i := strings.LastIndex(name, ".func")
+	if i < 0 {
+		return false
+	}
+	_, err := strconv.Atoi(strings.ReplaceAll(name[i+len(".func"):], ".", ""))
+	return err == nil
+}
+
+// outTypes returns the return types of a function except error, joined by commas.
+func outTypes(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	types := make([]string, 0, t.NumOut())
+	for i := 0; i < t.NumOut(); i++ {
+		if t.Out(i).Implements(errorType) {
+			continue
+		}
+		types = append(types, t.Out(i).String())
+	}
+	return strings.Join(types, ", ")
+}
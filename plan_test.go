@@ -0,0 +1,106 @@
+package funchain
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func double(n int) int {
+	return n * 2
+}
+
+func TestPlan(t *testing.T) {
+	plan := New(func() int {
+		return 1
+	}).ThenDoc("double the number", double).Then(New(double)).Plan()
+	if len(plan) != 3 {
+		t.Fatalf("unexpected plan length: expected 3, got %d", len(plan))
+	}
+	if plan[0].Doc != "" || plan[1].Doc != "double the number" {
+		t.Fatalf("unexpected docs: %q, %q", plan[0].Doc, plan[1].Doc)
+	}
+	if !strings.HasSuffix(plan[1].Name, ".double") {
+		t.Fatalf("unexpected name: %s", plan[1].Name)
+	}
+	if plan[1].Type != reflect.TypeOf(double) {
+		t.Fatalf("unexpected type: %v", plan[1].Type)
+	}
+	if plan[2].Index != 2 || plan[2].Name != "FunChain" || plan[2].Type != nil {
+		t.Fatalf("unexpected nested chain info: %+v", plan[2])
+	}
+}
+
+func TestDOT(t *testing.T) {
+	dot := New(func() int {
+		return 1
+	}).ThenDoc("double the number", double).Race(double, func(n int) (int, error) {
+		return n, nil
+	}).Then(strconv.Itoa).DOT()
+	for _, expected := range []string{
+		"digraph funchain {",
+		`n0 [label="step 0: func() int"];`,
+		`n1 [label="double the number"];`,
+		`n0 -> n1 [label="int"];`,
+		"subgraph cluster_2 {",
+		`label="Race";`,
+		`n2_0 [label="funchain.double"];`,
+		`n2_1 [label="step 1: func(int) (int, error)"];`,
+		`n1 -> n2_0 [label="int"];`,
+		`n1 -> n2_1 [label="int"];`,
+		`n2_1 -> n3 [label="int"];`,
+		`n3 [label="strconv.Itoa"];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Fatalf("DOT output should contain %q:\n%s", expected, dot)
+		}
+	}
+}
+
+func TestNameResolver(t *testing.T) {
+	inc := func(n int) int { return n + 1 }
+	crash := func(n int) int { panic("boom") }
+	names := map[uintptr]string{
+		reflect.ValueOf(inc).Pointer(): "inc",
+	}
+	fc := New(inc, double).WithNameResolver(func(fn interface{}) string {
+		return names[reflect.ValueOf(fn).Pointer()]
+	})
+	plan := fc.Plan()
+	if plan[0].Name != "inc" {
+		t.Fatalf("unexpected name: %s", plan[0].Name)
+	}
+	// 解析器返回空字符串时使用运行时的名称
+	if !strings.HasSuffix(plan[1].Name, ".double") {
+		t.Fatalf("unexpected fallback name: %s", plan[1].Name)
+	}
+
+	names[reflect.ValueOf(crash).Pointer()] = "crash"
+	_, err := fc.Then(crash).Do()
+	if err == nil || !strings.Contains(err.Error(), "crash") {
+		t.Fatalf("expected resolved name in error, got %v", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	fc := New(func() int {
+		return 1
+	}, double).ThenNamed("toText", strconv.Itoa).Then(strings.ToUpper).Delay(0)
+	want := "1:func() int -> 2:double -> 3:toText -> 4:ToUpper -> 5:Delay"
+	if got := fc.Describe(); got != want {
+		t.Fatalf("unexpected description:\n got: %s\nwant: %s", got, want)
+	}
+
+	// 名称在索引钩子中可通过 StepName 获取
+	var names []string
+	_, err := fc.BeforeIndexed(func(step int, fnType reflect.Type, input []interface{}) {
+		names = append(names, fc.StepName(step))
+	}).Do()
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if names[2] != "toText" || fc.StepName(10) != "" {
+		t.Fatalf("unexpected step names: %v", names)
+	}
+}
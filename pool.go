@@ -0,0 +1,93 @@
+package funchain
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrReleased is returned when executing a chain after it has been released.
+var ErrReleased = errors.New("chain has been released")
+
+// chainBuffers holds the slices of a chain created by NewPooled.
+type chainBuffers struct {
+	steps       []*step
+	defers      []func()
+	beforeHooks []beforeHook
+	afterHooks  []afterHook
+	errHooks    []errorHook
+}
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return &chainBuffers{}
+	},
+}
+
+// NewPooled creates a new function chain like New, but its internal slices are taken from a pool.
+// It is meant for high-QPS services building short-lived chains per request, the chain must be given
+// back with Release once it is no longer needed so that its slices can be reused.
+func NewPooled(fns ...interface{}) *FunChain {
+	buf := bufferPool.Get().(*chainBuffers)
+	fc := &FunChain{
+		pooled:      true,
+		steps:       buf.steps[:0],
+		defers:      buf.defers[:0],
+		beforeHooks: buf.beforeHooks[:0],
+		afterHooks:  buf.afterHooks[:0],
+		errHooks:    buf.errHooks[:0],
+	}
+	return fc.Then(fns...)
+}
+
+// Release returns the internal slices of a chain created by NewPooled to the pool.
+// The chain must not be used afterwards, executing it returns ErrReleased.
+// Calling Release more than once, or on a chain not created by NewPooled, such as a clone of a pooled
+// chain, has no effect.
+func (fc *FunChain) Release() {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if !fc.pooled || fc.released {
+		return
+	}
+	fc.released = true
+	// 清空元素，避免池中的切片持有函数引用
+	buf := &chainBuffers{
+		steps:       clearSlice(fc.steps),
+		defers:      clearSlice(fc.defers),
+		beforeHooks: clearSlice(fc.beforeHooks),
+		afterHooks:  clearSlice(fc.afterHooks),
+		errHooks:    clearSlice(fc.errHooks),
+	}
+	fc.steps, fc.defers, fc.beforeHooks, fc.afterHooks, fc.errHooks = nil, nil, nil, nil, nil
+	bufferPool.Put(buf)
+}
+
+// clearSlice sets all elements of s to their zero value and returns s truncated to length zero.
+func clearSlice[T any](s []T) []T {
+	var zero T
+	for i := range s {
+		s[i] = zero
+	}
+	return s[:0]
+}
+
+// Reset removes all steps, hooks and defers of the chain while keeping the capacity of its internal
+// slices, so that the same value can be refilled with another pipeline without allocating, e.g. when
+// chains are kept in a sync.Pool. Settings such as WithRetry or WithName are kept, stats and argument
+// history are cleared. Runs in flight are not affected.
+func (fc *FunChain) Reset() *FunChain {
+	fc.mu.Lock()
+	fc.steps = clearSlice(fc.steps)
+	fc.defers = clearSlice(fc.defers)
+	fc.beforeHooks = clearSlice(fc.beforeHooks)
+	fc.afterHooks = clearSlice(fc.afterHooks)
+	fc.errHooks = clearSlice(fc.errHooks)
+	fc.recoverHooks = clearSlice(fc.recoverHooks)
+	fc.mutateHooks = clearSlice(fc.mutateHooks)
+	fc.mu.Unlock()
+	fc.statsMu.Lock()
+	fc.stats = Stats{}
+	fc.statsMu.Unlock()
+	fc.setArgHistory(nil)
+	return fc
+}
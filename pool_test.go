@@ -0,0 +1,91 @@
+package funchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewPooled(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		var (
+			result   int
+			deferred bool
+		)
+		fc := NewPooled(func() int {
+			return i
+		}).Then(func(n int) int {
+			return n * 10
+		}).Defer(func() {
+			deferred = true
+		})
+		if len(fc.steps) != 2 || len(fc.defers) != 1 {
+			t.Fatalf("pooled chain should start empty: steps=%d, defers=%d", len(fc.steps), len(fc.defers))
+		}
+		_, err := fc.Do(&result)
+		if err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if result != i*10 || !deferred {
+			t.Fatalf("unexpected state: result=%d, deferred=%v", result, deferred)
+		}
+		fc.Release()
+		fc.Release()
+		if _, err = fc.Do(); !errors.Is(err, ErrReleased) {
+			t.Fatalf("expected ErrReleased, got %v", err)
+		}
+	}
+}
+
+func TestReleaseNotPooled(t *testing.T) {
+	// 非 NewPooled 创建的链调用 Release 无效，仍可执行
+	fc := New(func() int {
+		return 1
+	})
+	clone := NewPooled(double).Clone()
+	fc.Release()
+	clone.Release()
+	var result int
+	if _, err := fc.Do(&result); err != nil || result != 1 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	if _, err := clone.DoWith([]interface{}{2}); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	var (
+		before   int
+		deferred int
+	)
+	fc := New(func() int {
+		return 1
+	}, double).Before(func(input []interface{}) {
+		before++
+	}).Defer(func() {
+		deferred++
+	}).OnError(func(args []interface{}, err error) {
+		t.Fatal("error hook of the first pipeline called")
+	}).WithName("reused")
+	var result int
+	if _, err := fc.Do(&result); err != nil || result != 2 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+	stepsCap := cap(fc.steps)
+
+	fc.Reset().Then(func() string {
+		return "a"
+	}, strings.ToUpper)
+	var s string
+	if _, err := fc.Do(&s); err != nil || s != "A" {
+		t.Fatalf("unexpected state: s=%q, err=%v", s, err)
+	}
+	// 没有残留的步骤和钩子
+	if before != 2 || deferred != 1 || len(fc.steps) != 2 || len(fc.errHooks) != 0 {
+		t.Fatalf("leftovers after Reset: before=%d, deferred=%d, steps=%d", before, deferred, len(fc.steps))
+	}
+	if cap(fc.steps) != stepsCap || fc.name != "reused" || fc.Stats().Runs != 1 {
+		t.Fatalf("unexpected state: cap=%d, name=%q, runs=%d", cap(fc.steps), fc.name, fc.Stats().Runs)
+	}
+}
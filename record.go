@@ -0,0 +1,56 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Recording is the record of a run made by DoRecorded, it holds what is needed to replay the failed step.
+//
+// The recorded values are the real argument values, so replaying an in-memory Recording is exact.
+// A Recording can be persisted e.g. with encoding/json to reproduce a production failure locally, but only
+// values that survive the encoding can be replayed faithfully: JSON turns numbers into float64 and structs into
+// maps, unexported fields, channels and functions are lost, and gob requires the concrete types to be registered.
+type Recording struct {
+	// Inputs holds the arguments received by the steps of the chain, indexed by step.
+	// The entries of the steps that didn't run, e.g. after a failure, are nil.
+	Inputs [][]interface{}
+	// FailedStep is the index of the step that failed, -1 if the run succeeded.
+	FailedStep int
+	// Err is the message of the error of the failed step.
+	Err string
+}
+
+// record stores a copy of args as the inputs of step i, the steps skipped before it get nil inputs.
+func (r *Recording) record(i int, args []interface{}) {
+	for len(r.Inputs) < i {
+		r.Inputs = append(r.Inputs, nil)
+	}
+	r.Inputs = append(r.Inputs[:i], append(make([]interface{}, 0, len(args)), args...))
+}
+
+// DoRecorded executes the function chain like Do and returns the recording of the run.
+// The inputs are copied shallowly: values pointing to shared data, such as slices and pointers,
+// reflect later modifications of that data.
+func (fc *FunChain) DoRecorded(out ...interface{}) (rec *Recording, result []interface{}, err error) {
+	snap := fc.snapshot()
+	rs := newRunState(snap)
+	rs.recording = &Recording{FailedStep: -1}
+	_, result, err = snap.execute(rs, 0, snap.seedArgs(), out)
+	return rs.recording, result, err
+}
+
+// ReplayFailed re-executes the chain starting from the step that failed in rec, with the inputs that step
+// received in the recorded run, e.g. to debug a production failure locally with its real inputs.
+// The steps before the failed one are not executed. The chain must have the same steps as the recorded one.
+func (fc *FunChain) ReplayFailed(rec *Recording, out ...interface{}) ([]interface{}, error) {
+	if rec == nil || rec.FailedStep < 0 {
+		return nil, errors.New("recording has no failed step")
+	}
+	snap := fc.snapshot()
+	if rec.FailedStep >= len(snap.steps) || rec.FailedStep >= len(rec.Inputs) {
+		return nil, fmt.Errorf("failed step %d is out of range", rec.FailedStep)
+	}
+	_, result, err := snap.execute(newRunState(snap), rec.FailedStep, rec.Inputs[rec.FailedStep], out)
+	return result, err
+}
@@ -0,0 +1,89 @@
+package funchain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReplayFailed(t *testing.T) {
+	var (
+		loads  int
+		broken = true
+		result string
+	)
+	fc := New(func() (string, int) {
+		loads++
+		return "id", 42
+	}).Then(func(key string, n int) (string, error) {
+		if broken {
+			return "", errors.New("broken")
+		}
+		return key + "=" + string(rune('0'+n%10)), nil
+	})
+
+	rec, _, err := fc.DoRecorded()
+	if err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if rec.FailedStep != 1 || rec.Err != "broken" {
+		t.Fatalf("unexpected recording: %+v", rec)
+	}
+	if !reflect.DeepEqual(rec.Inputs[1], []interface{}{"id", 42}) {
+		t.Fatalf("unexpected recorded inputs: %v", rec.Inputs)
+	}
+
+	// 修复后只重放失败的步骤
+	broken = false
+	if _, err = fc.ReplayFailed(rec, &result); err != nil {
+		t.Fatal("Replay error:", err)
+	}
+	if result != "id=2" || loads != 1 {
+		t.Fatalf("unexpected replay: result=%s, loads=%d", result, loads)
+	}
+
+	rec, _, err = fc.DoRecorded()
+	if err != nil || rec.FailedStep != -1 {
+		t.Fatalf("unexpected recording of a successful run: %+v, %v", rec, err)
+	}
+	if _, err = fc.ReplayFailed(rec); err == nil {
+		t.Fatal("replaying a successful run should fail")
+	}
+}
+
+func TestReplayFailedAlways(t *testing.T) {
+	var (
+		broken = true
+		ran    bool
+		result int
+	)
+	fc := New(func() int {
+		return 1
+	}, func(n int) (int, error) {
+		return 0, errors.New("first")
+	}, func(n int) int {
+		ran = true
+		return n
+	}).ThenAlways(func(err error, n int) (int, error) {
+		if broken {
+			return 0, errors.New("cleanup failed")
+		}
+		return n + 10, nil
+	})
+	rec, _, err := fc.DoRecorded()
+	if err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	// 失败后跳过的步骤没有输入，下标与步骤一致，ThenAlways 收到失败步骤的参数
+	if rec.FailedStep != 3 || rec.Err != "cleanup failed" || len(rec.Inputs) != 4 || rec.Inputs[2] != nil {
+		t.Fatalf("unexpected recording: %+v", rec)
+	}
+
+	broken = false
+	if _, err = fc.ReplayFailed(rec, &result); err != nil {
+		t.Fatal("Replay error:", err)
+	}
+	if ran || !reflect.DeepEqual(rec.Inputs[3], []interface{}{1}) || result != 11 {
+		t.Fatalf("unexpected replay: ran=%v, result=%d, inputs=%v", ran, result, rec.Inputs[3])
+	}
+}
@@ -0,0 +1,52 @@
+package funchain
+
+import "reflect"
+
+// Redacted is the placeholder that RedactTypes substitutes for sensitive values.
+const Redacted = "[REDACTED]"
+
+// RedactFunc hides sensitive values from the arguments handed to hooks.
+// step: zero-based index of the step the arguments belong to.
+// args: a copy of the arguments, it may be modified in place.
+type RedactFunc func(step int, args []interface{}) []interface{}
+
+// RedactArgs sets a redactor applied to the argument slices passed to the Before, After and error hooks,
+// so that sensitive values such as passwords and tokens don't end up in logs.
+// Only the copies handed to hooks are redacted, the functions of the chain still receive the real values.
+func (fc *FunChain) RedactArgs(redactor RedactFunc) *FunChain {
+	return fc.Configure(RedactArgs(redactor))
+}
+
+// RedactArgs returns an Option doing the same as FunChain.RedactArgs.
+func RedactArgs(redactor RedactFunc) Option {
+	return func(fc *FunChain) {
+		fc.redactor = redactor
+	}
+}
+
+// redact returns the redacted copy of args for hooks, or args itself if no redactor is set.
+func (fc *FunChain) redact(step int, args []interface{}) []interface{} {
+	if fc.redactor == nil || len(args) == 0 {
+		return args
+	}
+	cp := make([]interface{}, len(args))
+	copy(cp, args)
+	return fc.redactor(step, cp)
+}
+
+// RedactTypes returns a redactor that replaces every argument having the same type as one of samples with Redacted.
+// e.g. RedactTypes(Password(""), &Token{}) masks all values of type Password and *Token.
+func RedactTypes(samples ...interface{}) RedactFunc {
+	types := make(map[reflect.Type]bool, len(samples))
+	for _, sample := range samples {
+		types[reflect.TypeOf(sample)] = true
+	}
+	return func(step int, args []interface{}) []interface{} {
+		for i, arg := range args {
+			if arg != nil && types[reflect.TypeOf(arg)] {
+				args[i] = Redacted
+			}
+		}
+		return args
+	}
+}
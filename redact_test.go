@@ -0,0 +1,42 @@
+package funchain
+
+import (
+	"errors"
+	"testing"
+)
+
+type password string
+
+func TestRedactArgs(t *testing.T) {
+	var (
+		seen     password
+		logged   [][]interface{}
+		errInput []interface{}
+	)
+	_, err := New(func() (string, password) {
+		return "admin", "secret"
+	}).Then(func(user string, pwd password) (string, password, error) {
+		seen = pwd
+		return user, pwd, errors.New("login failed")
+	}).After(func(input []interface{}, output []interface{}) {
+		logged = append(logged, input, output)
+	}).OnError(func(output []interface{}, err error) {
+		errInput = output
+	}).RedactArgs(RedactTypes(password(""))).Do()
+	if err == nil {
+		t.Fatal("expected error, but got nil")
+	}
+	if seen != "secret" {
+		t.Fatalf("function should receive the real value, got %q", seen)
+	}
+	for _, args := range append(logged, errInput) {
+		for _, arg := range args {
+			if _, ok := arg.(password); ok {
+				t.Fatalf("password leaked to hook: %v", args)
+			}
+		}
+	}
+	if len(errInput) != 2 || errInput[0] != "admin" || errInput[1] != Redacted {
+		t.Fatalf("unexpected error hook arguments: %v", errInput)
+	}
+}
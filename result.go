@@ -0,0 +1,62 @@
+package funchain
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Result is the results of a chain run with accessors that never panic, see DoResult.
+type Result []interface{}
+
+// DoResult executes the function chain like Do and returns its results as a Result.
+func (fc *FunChain) DoResult(out ...interface{}) (Result, error) {
+	_, result, err := fc.doBound(context.Background(), out)
+	return Result(result), err
+}
+
+// Get returns the value at index i, nil if i is out of range.
+func (r Result) Get(i int) interface{} {
+	if i < 0 || i >= len(r) {
+		return nil
+	}
+	return r[i]
+}
+
+// String returns the value at index i if it is a string.
+// ok is false if i is out of range or the value has another type.
+func (r Result) String(i int) (s string, ok bool) {
+	s, ok = r.Get(i).(string)
+	return s, ok
+}
+
+// Int returns the value at index i if it is an int.
+// ok is false if i is out of range or the value has another type.
+func (r Result) Int(i int) (n int, ok bool) {
+	n, ok = r.Get(i).(int)
+	return n, ok
+}
+
+// As assigns the value at index i to the variable out points to, like Do does with its out arguments.
+// An error is returned if i is out of range, out is not a non-nil pointer or the value isn't assignable to it.
+// A nil value sets the variable to its zero value.
+func (r Result) As(i int, out interface{}) error {
+	if i < 0 || i >= len(r) {
+		return fmt.Errorf("result index %d out of range [0, %d)", i, len(r))
+	}
+	ptr := reflect.ValueOf(out)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("out must be a non-nil pointer, got %T", out)
+	}
+	dst := ptr.Elem()
+	if r[i] == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	v := reflect.ValueOf(r[i])
+	if !v.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("result %d of type %s is not assignable to %s", i, v.Type(), dst.Type())
+	}
+	dst.Set(v)
+	return nil
+}
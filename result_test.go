@@ -0,0 +1,43 @@
+package funchain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDoResult(t *testing.T) {
+	r, err := New(func() (string, int, error) {
+		return "a", 1, nil
+	}).DoResult()
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if s, ok := r.String(0); !ok || s != "a" {
+		t.Fatalf("unexpected string: %q, %v", s, ok)
+	}
+	if n, ok := r.Int(1); !ok || n != 1 {
+		t.Fatalf("unexpected int: %d, %v", n, ok)
+	}
+	// 类型不符或越界时 ok 为 false，不会 panic
+	if _, ok := r.Int(0); ok {
+		t.Fatal("expected ok=false for a string read as int")
+	}
+	if _, ok := r.String(5); ok || r.Get(-1) != nil {
+		t.Fatal("expected no value for an out-of-range index")
+	}
+
+	var s string
+	if err := r.As(0, &s); err != nil || s != "a" {
+		t.Fatalf("unexpected state: s=%q, err=%v", s, err)
+	}
+	var f float64
+	if err := r.As(1, &f); err == nil || !strings.Contains(err.Error(), "int is not assignable to float64") {
+		t.Fatalf("expected type error, got %v", err)
+	}
+	if err := r.As(2, &s); err == nil || !strings.Contains(err.Error(), "out of range") {
+		t.Fatalf("expected range error, got %v", err)
+	}
+	if err := r.As(0, s); err == nil {
+		t.Fatal("expected error for a non-pointer out")
+	}
+}
@@ -0,0 +1,64 @@
+package funchain
+
+import (
+	"errors"
+	"time"
+)
+
+// WithRetry makes a failing step run again, up to attempts runs in total, before the error hooks are
+// called and the chain is aborted. backoff returns the time to wait before the given retry (1 for the
+// first one) using the chain's clock, it may be nil to retry immediately. The wait ends early once the
+// context of the run is done, the step then fails with the output and the error of the last attempt joined
+// with the error of the context.
+// Only the failing step is run again, the steps before it are not. When all attempts fail, the error of
+// the last one is returned. The step timeout set with WithStepTimeout applies to each attempt.
+func (fc *FunChain) WithRetry(attempts int, backoff func(attempt int) time.Duration) *FunChain {
+	return fc.Configure(WithRetry(attempts, backoff))
+}
+
+// WithRetry returns an Option doing the same as FunChain.WithRetry.
+func WithRetry(attempts int, backoff func(attempt int) time.Duration) Option {
+	return func(fc *FunChain) {
+		fc.retries = attempts
+		fc.retryBackoff = backoff
+	}
+}
+
+// WithRetryIf is like WithRetry without backoff, but only the errors for which retryable returns true
+// cause a step to run again, other errors fail the chain at once.
+// It can be combined with WithRetry to also wait between the attempts, the later call sets attempts.
+func (fc *FunChain) WithRetryIf(attempts int, retryable func(err error) bool) *FunChain {
+	return fc.Configure(WithRetryIf(attempts, retryable))
+}
+
+// WithRetryIf returns an Option doing the same as FunChain.WithRetryIf.
+func WithRetryIf(attempts int, retryable func(err error) bool) Option {
+	return func(fc *FunChain) {
+		fc.retries = attempts
+		fc.retryIf = retryable
+	}
+}
+
+// execStepRetry executes the step at index i like execStepTimeout, running it again on error as set with WithRetry.
+func (fc *FunChain) execStepRetry(rs *runState, i int, st *step, args []interface{}) ([]interface{}, error) {
+	result, err := fc.execStepTimeout(rs, i, st, args)
+	for attempt := 1; attempt < fc.retries && fc.shouldRetry(rs, err); attempt++ {
+		if fc.retryBackoff != nil {
+			select {
+			case <-fc.getClock().After(fc.retryBackoff(attempt)):
+			case <-rs.ctx.Done():
+				return result, errors.Join(err, rs.ctx.Err())
+			}
+		}
+		result, err = fc.execStepTimeout(rs, i, st, args)
+	}
+	return result, err
+}
+
+// shouldRetry reports whether a step failing with err should run again.
+func (fc *FunChain) shouldRetry(rs *runState, err error) bool {
+	if err == nil || errors.Is(err, ErrStopChain) || rs.ctx.Err() != nil {
+		return false
+	}
+	return fc.retryIf == nil || fc.retryIf(err)
+}
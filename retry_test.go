@@ -0,0 +1,111 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	var (
+		before   int
+		attempts int
+		hooked   int
+		waits    []time.Duration
+		result   int
+	)
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	flaky := func(n int) (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, errFlaky
+		}
+		return n * 2, nil
+	}
+	backoff := func(attempt int) time.Duration {
+		waits = append(waits, time.Duration(attempt)*time.Second)
+		return time.Duration(attempt) * time.Second
+	}
+	_, err := New(func() int {
+		before++
+		return 21
+	}, flaky).WithClock(clock).WithRetry(3, backoff).OnError(func(args []interface{}, err error) {
+		hooked++
+	}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != 42 || attempts != 3 || before != 1 || hooked != 0 {
+		t.Fatalf("unexpected state: result=%d, attempts=%d, before=%d, hooked=%d", result, attempts, before, hooked)
+	}
+	if len(waits) != 2 || clock.now.Sub(start) != 3*time.Second {
+		t.Fatalf("unexpected backoff: waits=%v, elapsed=%v", waits, clock.now.Sub(start))
+	}
+
+	// 次数用尽后返回最后的错误并调用错误钩子
+	attempts = -10
+	_, err = New(flaky).WithRetry(2, nil).OnError(func(args []interface{}, err error) {
+		hooked++
+	}).Do()
+	if !errors.Is(err, errFlaky) || attempts != -8 || hooked != 1 {
+		t.Fatalf("unexpected state: err=%v, attempts=%d, hooked=%d", err, attempts, hooked)
+	}
+}
+
+func TestWithRetryCanceledBackoff(t *testing.T) {
+	errFlaky := errors.New("flaky")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		attempts int
+		output   []interface{}
+	)
+	_, err := New(func() (int, error) {
+		attempts++
+		return 7, errFlaky
+	}).WithRetry(3, func(attempt int) time.Duration {
+		// 等待重试期间 context 结束
+		cancel()
+		return time.Hour
+	}).OnError(func(args []interface{}, err error) {
+		output = args
+	}).DoContext(ctx)
+	// 返回最后一次的输出和错误，并附上 context 的错误
+	if !errors.Is(err, errFlaky) || !errors.Is(err, context.Canceled) || attempts != 1 {
+		t.Fatalf("unexpected state: err=%v, attempts=%d", err, attempts)
+	}
+	if len(output) != 1 || output[0] != 7 {
+		t.Fatalf("expected the output of the last attempt, got %v", output)
+	}
+}
+
+func TestWithRetryIf(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+	var (
+		attempts int
+		failWith error
+	)
+	fc := New(func() error {
+		attempts++
+		if attempts < 3 {
+			return failWith
+		}
+		return nil
+	}).WithRetryIf(5, func(err error) bool {
+		return errors.Is(err, errTemporary)
+	})
+	failWith = errTemporary
+	if _, err := fc.Do(); err != nil || attempts != 3 {
+		t.Fatalf("unexpected state: err=%v, attempts=%d", err, attempts)
+	}
+	// 不可重试的错误立即失败
+	attempts = 0
+	failWith = errPermanent
+	if _, err := fc.Do(); !errors.Is(err, errPermanent) || attempts != 1 {
+		t.Fatalf("unexpected state: err=%v, attempts=%d", err, attempts)
+	}
+}
@@ -0,0 +1,58 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// compensable is a completed step whose compensation runs if the chain fails later.
+type compensable struct {
+	index   int
+	fn      interface{}
+	outputs []interface{}
+}
+
+// ThenCompensable adds a function along with a compensation undoing its effects, e.g. cancelling a
+// reservation, for steps that can't share a transaction (saga pattern).
+// If the chain fails after action completed, the compensations of all completed compensable steps run in
+// reverse order. Each compensation receives the return values of its action, apart from the error.
+// Compensations don't run when the action itself fails, or when the error was cleared by ThenAlways.
+// Errors and panics of the compensations are joined with the error of the chain, all compensations run
+// regardless. They run before the defer functions.
+func (fc *FunChain) ThenCompensable(action, compensation interface{}) *FunChain {
+	if !isFunc(action) {
+		return fc
+	}
+	st := &step{fn: action}
+	if isFunc(compensation) {
+		st.compensation = compensation
+	}
+	fc.addStep(st)
+	return fc
+}
+
+// ThenWithRollback adds a function along with a rollback undoing its effects, like ThenCompensable for
+// rollbacks that don't need the return values of fn: if a later function fails, the rollbacks of the
+// completed steps run in reverse order, before and independently of the defer functions.
+// A panicking rollback doesn't prevent the others from running, its panic is joined with the error of the chain.
+func (fc *FunChain) ThenWithRollback(fn interface{}, rollback func()) *FunChain {
+	if rollback == nil {
+		return fc.ThenCompensable(fn, nil)
+	}
+	return fc.ThenCompensable(fn, rollback)
+}
+
+// compensate runs the compensations of completed in reverse order and joins their errors with err.
+func (fc *FunChain) compensate(completed []compensable, err error) error {
+	if len(completed) == 0 {
+		return err
+	}
+	errs := []error{err}
+	for i := len(completed) - 1; i >= 0; i-- {
+		c := completed[i]
+		if _, cerr := execFunc(c.fn, c.outputs, fc.zeroProvider); cerr != nil {
+			errs = append(errs, fmt.Errorf("compensation of step %d: %w", c.index, cerr))
+		}
+	}
+	return errors.Join(errs...)
+}
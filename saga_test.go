@@ -0,0 +1,76 @@
+package funchain
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestThenCompensable(t *testing.T) {
+	var log []string
+	errPayment := errors.New("payment declined")
+	fc := New().ThenCompensable(func() (string, error) {
+		log = append(log, "reserve")
+		return "R1", nil
+	}, func(id string) {
+		log = append(log, "release "+id)
+	}).ThenCompensable(func(id string) (string, int) {
+		log = append(log, "ship")
+		return "S1", 3
+	}, func(id string, n int) error {
+		log = append(log, "cancel shipment")
+		return errors.New("carrier unavailable")
+	}).Then(func(id string, n int) error {
+		return errPayment
+	})
+	_, err := fc.Do()
+	if !errors.Is(err, errPayment) {
+		t.Fatalf("expected original error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "compensation of step 1: carrier unavailable") {
+		t.Fatalf("compensation error should be joined, got %v", err)
+	}
+	// 逆序补偿
+	expected := []string{"reserve", "ship", "cancel shipment", "release R1"}
+	if !reflect.DeepEqual(log, expected) {
+		t.Fatalf("unexpected execution order: %v", log)
+	}
+
+	// 成功时不补偿
+	log = nil
+	_, err = New().ThenCompensable(func() int {
+		return 1
+	}, func(n int) {
+		log = append(log, "compensated")
+	}).Do()
+	if err != nil || len(log) != 0 {
+		t.Fatalf("unexpected state: log=%v, err=%v", log, err)
+	}
+}
+
+func TestThenWithRollback(t *testing.T) {
+	var rolledBack []string
+	rollback := func(name string) func() {
+		return func() {
+			rolledBack = append(rolledBack, name)
+		}
+	}
+	errConfigure := errors.New("configure failed")
+	_, err := New().ThenWithRollback(func() string {
+		return "resource"
+	}, rollback("create")).ThenWithRollback(func(id string) string {
+		return id
+	}, func() {
+		panic("rollback failed")
+	}).ThenWithRollback(func(id string) error {
+		return errConfigure
+	}, rollback("configure")).Then(func() {}).Do()
+	if !errors.Is(err, errConfigure) || !errors.Is(err, ErrPanic) {
+		t.Fatalf("expected the step error and the rollback panic, got %v", err)
+	}
+	// 只回滚成功的步骤，某个回滚 panic 不影响其他回滚
+	if !reflect.DeepEqual(rolledBack, []string{"create"}) {
+		t.Fatalf("unexpected rollbacks: %v", rolledBack)
+	}
+}
@@ -0,0 +1,109 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Severity is the severity of a step error as determined by the classifier set with Classify.
+type Severity int
+
+const (
+	// SeverityInfo is for expected conditions that don't need attention, such as io.EOF.
+	SeverityInfo Severity = iota
+	// SeverityWarn is for errors that may need attention, such as timeouts.
+	SeverityWarn
+	// SeverityCritical is for errors that need attention.
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarn:
+		return "warn"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// classifiedError wraps a step error along with its severity.
+type classifiedError struct {
+	err      error
+	severity Severity
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+// Severity returns the severity of the error.
+func (e *classifiedError) Severity() Severity {
+	return e.severity
+}
+
+// Classify sets a classifier that assigns a severity to every step error.
+// The classifier runs before the error hooks, the error they receive and the one returned by Do
+// implements a Severity() Severity method, which can be read with SeverityOf.
+// DefaultClassifier handles common standard library errors. If fn panics the error is left unclassified.
+func (fc *FunChain) Classify(fn func(error) Severity) *FunChain {
+	return fc.Configure(Classify(fn))
+}
+
+// Classify returns an Option doing the same as FunChain.Classify.
+func Classify(fn func(error) Severity) Option {
+	return func(fc *FunChain) {
+		fc.classifier = fn
+	}
+}
+
+// classify wraps err with its severity if a classifier is set and err isn't classified yet.
+// If the classifier panics err is returned unclassified.
+func (fc *FunChain) classify(err error) (classified error) {
+	if fc.classifier == nil {
+		return err
+	}
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Panic from classifier:", r)
+			classified = err
+		}
+	}()
+	return &classifiedError{err: err, severity: fc.classifier(err)}
+}
+
+// SeverityOf returns the severity of an error returned by a chain with a classifier.
+// ok is false if the error was not classified.
+func SeverityOf(err error) (severity Severity, ok bool) {
+	var s interface{ Severity() Severity }
+	if errors.As(err, &s) {
+		return s.Severity(), true
+	}
+	return SeverityInfo, false
+}
+
+// DefaultClassifier classifies io.EOF and context.Canceled as info,
+// context.DeadlineExceeded, os.ErrNotExist and os.ErrPermission as warn, and everything else,
+// including panics, as critical.
+func DefaultClassifier(err error) Severity {
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, context.Canceled):
+		return SeverityInfo
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrNotExist), errors.Is(err, os.ErrPermission):
+		return SeverityWarn
+	}
+	return SeverityCritical
+}
@@ -0,0 +1,57 @@
+package funchain
+
+import (
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	for _, c := range []struct {
+		fn       interface{}
+		expected Severity
+	}{
+		{func() error { return io.EOF }, SeverityInfo},
+		{func() error { return &os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist} }, SeverityWarn},
+		{func() error { return errors.New("failed") }, SeverityCritical},
+		{func() { panic("boom") }, SeverityCritical},
+	} {
+		var hookSeverity Severity = -1
+		_, err := New(c.fn).Classify(DefaultClassifier).OnError(func(output []interface{}, err error) {
+			hookSeverity, _ = SeverityOf(err)
+		}).Do()
+		severity, ok := SeverityOf(err)
+		if !ok {
+			t.Fatalf("error should be classified: %v", err)
+		}
+		if severity != c.expected || hookSeverity != c.expected {
+			t.Fatalf("unexpected severity of %v: expected %s, got %s (hook: %s)", err, c.expected, severity, hookSeverity)
+		}
+	}
+
+	// 分类不影响原始错误的判断
+	_, err := New(func() error { return io.EOF }).Classify(DefaultClassifier).Do()
+	if !errors.Is(err, io.EOF) || err.Error() != io.EOF.Error() {
+		t.Fatalf("classified error should wrap the original error, got %v", err)
+	}
+	if _, ok := SeverityOf(errors.New("plain")); ok {
+		t.Fatal("plain error should not be classified")
+	}
+}
+
+func TestClassifyPanic(t *testing.T) {
+	var hooked error
+	_, err := New(func() error { return io.EOF }).Classify(func(err error) Severity {
+		panic("boom")
+	}).OnError(func(output []interface{}, err error) {
+		hooked = err
+	}).Do()
+	// 分类函数 panic 时返回未分类的原始错误
+	if !errors.Is(err, io.EOF) || !errors.Is(hooked, io.EOF) {
+		t.Fatalf("expected the original error, got %v (hook: %v)", err, hooked)
+	}
+	if _, ok := SeverityOf(err); ok {
+		t.Fatal("error should not be classified when the classifier panics")
+	}
+}
@@ -0,0 +1,84 @@
+package funchain
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// FromStruct creates a function chain from methods of s, executed in the order of methodNames.
+// s: the receiver of the methods, pass a pointer to also include methods with a pointer receiver.
+// methodNames: names of the exported methods to chain.
+// An error is returned if s has no method with one of the given names.
+func FromStruct(s interface{}, methodNames ...string) (*FunChain, error) {
+	v := reflect.ValueOf(s)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("cannot build a chain from %v", s)
+	}
+	fns := make([]interface{}, 0, len(methodNames))
+	for _, name := range methodNames {
+		m := v.MethodByName(name)
+		if !m.IsValid() {
+			return nil, fmt.Errorf("%T has no method %q", s, name)
+		}
+		fns = append(fns, m.Interface())
+	}
+	return New(fns...), nil
+}
+
+// FromLines returns a function that reads r line by line and returns the lines without line endings,
+// it is meant to be the first step of a text processing chain, e.g. New(FromLines(f)).FlatMap(...).
+// Read errors, including lines longer than bufio.MaxScanTokenSize, fail the chain.
+// All the lines are loaded into memory, see StreamLines for large inputs. r is consumed by the first run,
+// further runs of the chain get no lines unless r can be read again.
+func FromLines(r io.Reader) func() ([]string, error) {
+	return func() ([]string, error) {
+		lines := make([]string, 0)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return lines, nil
+	}
+}
+
+// StreamLines runs the chain with Stream over the lines of r: every line, without its line ending, is
+// the argument of the first function and the results of the last function for each line are sent to out.
+// r is read as the lines are processed, so large files are never loaded into memory at once.
+// Read errors, including lines longer than bufio.MaxScanTokenSize, stop the pipeline and are returned,
+// as are the errors of the steps and ctx.Err(). out is always closed.
+func (fc *FunChain) StreamLines(ctx context.Context, r io.Reader, out chan<- []interface{}) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	in := make(chan []interface{})
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(in)
+		readErr <- sendLines(ctx, r, in)
+	}()
+	if err := fc.Stream(ctx, in, out); err != nil {
+		// 停止读取，返回步骤的错误
+		cancel()
+		<-readErr
+		return err
+	}
+	return <-readErr
+}
+
+// sendLines sends the lines of r to in until r is exhausted or ctx is done.
+func sendLines(ctx context.Context, r io.Reader, in chan<- []interface{}) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		select {
+		case in <- []interface{}{scanner.Text()}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return scanner.Err()
+}
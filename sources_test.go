@@ -0,0 +1,118 @@
+package funchain
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+type counter struct {
+	base int
+}
+
+func (c counter) Start() int {
+	return c.base
+}
+
+func (c *counter) Add(n int) int {
+	return n + c.base
+}
+
+func (c *counter) Format(n int) string {
+	return string(rune('a' + n))
+}
+
+func TestFromStruct(t *testing.T) {
+	var result string
+	fc, err := FromStruct(&counter{base: 1}, "Start", "Add", "Format")
+	if err != nil {
+		t.Fatal("FromStruct error:", err)
+	}
+	_, err = fc.Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "c" {
+		t.Fatalf("unexpected result: expected 'c', got %q", result)
+	}
+
+	// 指针接收者的方法不在值类型的方法集中
+	if _, err = FromStruct(counter{}, "Start", "Add"); err == nil {
+		t.Fatal("expected error for method with pointer receiver, but got nil")
+	}
+	if _, err = FromStruct(&counter{}, "Missing"); err == nil {
+		t.Fatal("expected error for unknown method, but got nil")
+	}
+}
+
+func TestFromLines(t *testing.T) {
+	var count int
+	_, err := New(FromLines(strings.NewReader("foo bar\nbaz\r\n\nqux"))).Then(func(lines []string) []string {
+		if !reflect.DeepEqual(lines, []string{"foo bar", "baz", "", "qux"}) {
+			t.Fatalf("unexpected lines: %q", lines)
+		}
+		return lines
+	}).FlatMap(strings.Fields).Then(func(words []string) int {
+		return len(words)
+	}).Do(&count)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if count != 4 {
+		t.Fatalf("unexpected word count: expected 4, got %d", count)
+	}
+
+	// 扫描错误会中断函数链
+	_, err = New(FromLines(strings.NewReader(strings.Repeat("x", bufio.MaxScanTokenSize+1)))).Do()
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("expected bufio.ErrTooLong, got %v", err)
+	}
+}
+
+func TestStreamLines(t *testing.T) {
+	fc := New(func(line string) int {
+		return len(strings.Fields(line))
+	})
+	collect := func(r io.Reader) ([]int, error) {
+		out := make(chan []interface{})
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- fc.StreamLines(context.Background(), r, out)
+		}()
+		var counts []int
+		for result := range out {
+			counts = append(counts, result[0].(int))
+		}
+		return counts, <-errCh
+	}
+	counts, err := collect(strings.NewReader("foo bar\nbaz\r\n\nqux quux corge"))
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 1, 0, 3}) {
+		t.Fatalf("unexpected word counts: %v", counts)
+	}
+
+	// 读取错误在已读取的行处理完后返回
+	errRead := errors.New("read failed")
+	counts, err = collect(io.MultiReader(strings.NewReader("a b\nc\n"), iotest.ErrReader(errRead)))
+	if !errors.Is(err, errRead) {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if !reflect.DeepEqual(counts, []int{2, 1}) {
+		t.Fatalf("unexpected word counts: %v", counts)
+	}
+
+	// 步骤出错时停止读取
+	err = New(func(line string) error {
+		return errors.New("bad line " + line)
+	}).StreamLines(context.Background(), strings.NewReader("x\ny\n"), make(chan []interface{}, 2))
+	if err == nil || err.Error() != "bad line x" {
+		t.Fatalf("expected the step error, got %v", err)
+	}
+}
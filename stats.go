@@ -0,0 +1,47 @@
+package funchain
+
+import "errors"
+
+// Stats holds the execution counters of a chain.
+type Stats struct {
+	// Runs is the number of times the chain was executed.
+	Runs int64
+	// Successes is the number of runs that completed without error.
+	Successes int64
+	// Failures is the number of runs that returned an error.
+	Failures int64
+	// Panics is the number of failed runs caused by a panic, they are also counted in Failures.
+	Panics int64
+}
+
+// Stats returns the execution counters of the chain, updated on each Do.
+// It is safe to call concurrently with Do.
+func (fc *FunChain) Stats() Stats {
+	fc.statsMu.Lock()
+	defer fc.statsMu.Unlock()
+	return fc.stats
+}
+
+// ResetStats sets all execution counters of the chain back to zero.
+func (fc *FunChain) ResetStats() *FunChain {
+	fc.statsMu.Lock()
+	fc.stats = Stats{}
+	fc.statsMu.Unlock()
+	return fc
+}
+
+// recordRun updates the execution counters with the outcome of a run.
+func (fc *FunChain) recordRun(err error) {
+	var pe *PanicError
+	fc.statsMu.Lock()
+	defer fc.statsMu.Unlock()
+	fc.stats.Runs++
+	if err == nil {
+		fc.stats.Successes++
+		return
+	}
+	fc.stats.Failures++
+	if errors.As(err, &pe) {
+		fc.stats.Panics++
+	}
+}
@@ -0,0 +1,40 @@
+package funchain
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	n := 0
+	fc := New(func() error {
+		n++
+		switch n % 3 {
+		case 1:
+			return errors.New("failed")
+		case 2:
+			panic("boom")
+		}
+		return nil
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = fc.Stats()
+		}()
+	}
+	for i := 0; i < 6; i++ {
+		_, _ = fc.Do()
+	}
+	wg.Wait()
+	expected := Stats{Runs: 6, Successes: 2, Failures: 4, Panics: 2}
+	if stats := fc.Stats(); stats != expected {
+		t.Fatalf("unexpected stats: expected %+v, got %+v", expected, stats)
+	}
+	if stats := fc.ResetStats().Stats(); stats != (Stats{}) {
+		t.Fatalf("stats should be reset, got %+v", stats)
+	}
+}
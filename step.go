@@ -0,0 +1,70 @@
+package funchain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Step is a step of a chain implemented as an object rather than a plain function, e.g. to keep its own
+// state such as retries, caches or metrics. It is called without reflection: Exec receives the arguments
+// as they are and its results are passed on to the next step.
+// A Step is added to a chain with New or Then like a function.
+type Step interface {
+	// Name returns the name of the step, reported by Plan, hooks and events.
+	Name() string
+	// Exec executes the step.
+	// ctx: context of the run.
+	// args: return values of the previous step.
+	Exec(ctx context.Context, args []interface{}) ([]interface{}, error)
+}
+
+// ContextStep is a Step that can also replace the context of the run, e.g. to attach metadata such as
+// OpenTelemetry baggage. The context it returns is passed to the steps after it, a nil context keeps the
+// current one. The context isn't replaced when the step fails.
+type ContextStep interface {
+	Step
+	ExecContext(ctx context.Context, args []interface{}) (context.Context, []interface{}, error)
+}
+
+// funcStep is a Step calling a plain function.
+type funcStep struct {
+	fn interface{}
+}
+
+// AsStep wraps a plain function as a Step, its arguments and results are handled like those of a function
+// added with Then. If fn is not a function, Exec returns an error.
+func AsStep(fn interface{}) Step {
+	return &funcStep{fn: fn}
+}
+
+// Name returns the name of the wrapped function.
+func (s *funcStep) Name() string {
+	if !isFunc(s.fn) {
+		return fmt.Sprintf("%T", s.fn)
+	}
+	return funcName(s.fn)
+}
+
+// Exec calls the wrapped function with args.
+func (s *funcStep) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	return execFunc(s.fn, args, nil)
+}
+
+// execStepObject executes s, a panic in Exec is turned into an error like for functions.
+// The context of rs is replaced if s is a ContextStep.
+func execStepObject(rs *runState, s Step, args []interface{}) (result []interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, newPanicError(s.Name(), r)
+		}
+	}()
+	cs, ok := s.(ContextStep)
+	if !ok {
+		return s.Exec(rs.ctx, args)
+	}
+	ctx, result, err := cs.ExecContext(rs.ctx, args)
+	if err == nil && ctx != nil {
+		rs.ctx = ctx
+	}
+	return result, err
+}
@@ -0,0 +1,126 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// counterStep 是一个有状态的步骤，记录自己被执行的次数
+type counterStep struct {
+	calls int
+}
+
+func (s *counterStep) Name() string {
+	return "counter"
+}
+
+func (s *counterStep) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	if ctx == nil {
+		return nil, errors.New("missing context")
+	}
+	s.calls++
+	return []interface{}{args[0].(int) + s.calls}, nil
+}
+
+func TestStep(t *testing.T) {
+	counter := &counterStep{}
+	fc := New(func() int {
+		return 10
+	}).Then(counter, AsStep(double))
+	var result int
+	for i, expected := range []int{22, 24} {
+		if _, err := fc.Do(&result); err != nil {
+			t.Fatal("Chain execution error:", err)
+		}
+		if result != expected {
+			t.Fatalf("run %d: unexpected result: expected %d, got %d", i, expected, result)
+		}
+	}
+	plan := fc.Plan()
+	if plan[1].Name != "counter" || plan[1].Type != nil {
+		t.Fatalf("unexpected plan for Step: %+v", plan[1])
+	}
+
+	// 非函数的 AsStep 在执行时报错
+	if _, err := New(AsStep(42)).Do(); err == nil {
+		t.Fatal("expected error for AsStep of a non-function")
+	}
+}
+
+type ctxKey struct{}
+
+// tagStep 把参数写入 context，供后续步骤读取
+type tagStep struct{}
+
+func (tagStep) Name() string {
+	return "tag"
+}
+
+func (tagStep) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	return args, nil
+}
+
+func (tagStep) ExecContext(ctx context.Context, args []interface{}) (context.Context, []interface{}, error) {
+	return context.WithValue(ctx, ctxKey{}, args[0]), args, nil
+}
+
+// readStep 返回 context 中的值，没有值时返回空字符串
+type readStep struct{}
+
+func (readStep) Name() string {
+	return "read"
+}
+
+func (readStep) Exec(ctx context.Context, args []interface{}) ([]interface{}, error) {
+	v, _ := ctx.Value(ctxKey{}).(string)
+	return []interface{}{v}, nil
+}
+
+func TestContextStep(t *testing.T) {
+	var result string
+	_, err := New(func() string {
+		return "tagged"
+	}).Then(tagStep{}, func(s string) int {
+		return len(s)
+	}, readStep{}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "tagged" {
+		t.Fatalf("unexpected result: %q", result)
+	}
+}
+
+func TestThenWithValue(t *testing.T) {
+	var (
+		seen   []interface{}
+		result string
+	)
+	tag := func(ctx context.Context, s string) string {
+		seen = append(seen, ctx.Value(ctxKey{}))
+		return s
+	}
+	_, err := New(func() string {
+		return "x"
+	}).ThenWithValue(ctxKey{}, "first", tag).
+		ThenWithValue(ctxKey{}, "second", tag).
+		Then(readStep{}).Do(&result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if len(seen) != 2 || seen[0] != "first" || seen[1] != "second" {
+		t.Fatalf("unexpected values: %v", seen)
+	}
+	// 值不会泄漏到后续步骤
+	if result != "" {
+		t.Fatalf("value leaked into the next step: %q", result)
+	}
+
+	_, err = New(func() string {
+		return "x"
+	}).ThenWithValue(ctxKey{}, "step", readStep{}).Do(&result)
+	if err != nil || result != "step" {
+		t.Fatalf("unexpected state: result=%q, err=%v", result, err)
+	}
+}
@@ -0,0 +1,79 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStepTimeout is wrapped by the error returned when a step doesn't complete within the limit set
+// by WithStepTimeout.
+var ErrStepTimeout = errors.New("step timed out")
+
+// AbandonedStepFunc is called when a step timed out and is left running in the background.
+type AbandonedStepFunc func(step int, name string)
+
+// WithStepTimeout limits how long each step may run, measured with the chain's clock. When a step doesn't
+// return within d the chain fails with an error wrapping ErrStepTimeout, e.g. "step 2 timed out after 5s",
+// which is passed to the error hooks like any other error.
+// Functions are called through reflection and can't be interrupted: a step that timed out keeps running
+// in its own goroutine until it returns by itself and its results are discarded. Steps that may hang forever
+// thus leak goroutines, OnAbandonedStep can be used to log these cases. Functions taking a context.Context
+// can stop early by watching it with DoContext. d <= 0 disables the limit, which is the default.
+func (fc *FunChain) WithStepTimeout(d time.Duration) *FunChain {
+	return fc.Configure(WithStepTimeout(d))
+}
+
+// WithStepTimeout returns an Option doing the same as FunChain.WithStepTimeout.
+func WithStepTimeout(d time.Duration) Option {
+	return func(fc *FunChain) {
+		fc.stepTimeout = d
+	}
+}
+
+// OnAbandonedStep sets a function called whenever a step timed out and is left running, see WithStepTimeout.
+func (fc *FunChain) OnAbandonedStep(fn AbandonedStepFunc) *FunChain {
+	return fc.Configure(OnAbandonedStep(fn))
+}
+
+// OnAbandonedStep returns an Option doing the same as FunChain.OnAbandonedStep.
+func OnAbandonedStep(fn AbandonedStepFunc) Option {
+	return func(fc *FunChain) {
+		fc.onAbandoned = fn
+	}
+}
+
+// execStepTimeout executes the step at index i like execStep, within the step timeout if one is set.
+func (fc *FunChain) execStepTimeout(rs *runState, i int, st *step, args []interface{}) ([]interface{}, error) {
+	if fc.stepTimeout <= 0 {
+		return fc.execStep(rs, st, args)
+	}
+	type stepResult struct {
+		result []interface{}
+		err    error
+	}
+	// 超时后函数仍在后台运行，使用独立的运行状态避免数据竞争
+	branch := rs.fork()
+	done := make(chan stepResult, 1)
+	go func() {
+		result, err := fc.execStep(branch, st, args)
+		done <- stepResult{result, err}
+	}()
+	select {
+	case r := <-done:
+		rs.ctx = branch.ctx
+		return r.result, r.err
+	case <-fc.getClock().After(fc.stepTimeout):
+		if fc.onAbandoned != nil {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						fmt.Println("Panic from abandoned step handler:", r)
+					}
+				}()
+				fc.onAbandoned(i, fc.stepName(st))
+			}()
+		}
+		return nil, fmt.Errorf("step %d timed out after %s: %w", i, fc.stepTimeout, ErrStepTimeout)
+	}
+}
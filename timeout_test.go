@@ -0,0 +1,55 @@
+package funchain
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStepTimeout(t *testing.T) {
+	var (
+		abandoned int32
+		hookErr   error
+	)
+	release := make(chan struct{})
+	defer close(release)
+	fc := New(func() int {
+		return 1
+	}, func(n int) int {
+		return n + 1
+	}, func(n int) int {
+		// 超过时限的函数
+		<-release
+		return n
+	}).WithStepTimeout(20 * time.Millisecond).OnAbandonedStep(func(step int, name string) {
+		atomic.StoreInt32(&abandoned, int32(step))
+	}).OnError(func(args []interface{}, err error) {
+		hookErr = err
+	})
+	start := time.Now()
+	_, err := fc.Do()
+	if !errors.Is(err, ErrStepTimeout) || !strings.Contains(err.Error(), "step 2 timed out after 20ms") {
+		t.Fatalf("expected step timeout error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Do should return once the step timed out")
+	}
+	if !errors.Is(hookErr, ErrStepTimeout) {
+		t.Fatalf("error hook should receive the timeout, got %v", hookErr)
+	}
+	if atomic.LoadInt32(&abandoned) != 2 {
+		t.Fatalf("abandoned handler should report step 2, got %d", abandoned)
+	}
+
+	// 在时限内完成的步骤不受影响
+	var result int
+	_, err = New(func() int {
+		time.Sleep(time.Millisecond)
+		return 3
+	}).WithStepTimeout(time.Second).Do(&result)
+	if err != nil || result != 3 {
+		t.Fatalf("unexpected state: result=%d, err=%v", result, err)
+	}
+}
@@ -0,0 +1,99 @@
+package funchain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Tracer starts a span around every executed step of a chain, see WithTracer.
+// It is small enough to be adapted to OpenTelemetry or any other tracing library without funchain
+// depending on it.
+type Tracer interface {
+	// StartSpan starts a span named name as a child of the span in ctx, and returns the context carrying
+	// the new span, the key under which the span is stored in that context, and the function ending it.
+	// end receives the error of the step, nil on success.
+	StartSpan(ctx context.Context, name string) (spanCtx context.Context, key interface{}, end func(err error))
+}
+
+// TracerFunc adapts a function to a Tracer.
+type TracerFunc func(ctx context.Context, name string) (context.Context, interface{}, func(err error))
+
+// StartSpan calls f.
+func (f TracerFunc) StartSpan(ctx context.Context, name string) (context.Context, interface{}, func(err error)) {
+	return f(ctx, name)
+}
+
+// WithTracer makes the chain run every step inside a span started with t and named after the step,
+// see StepName. The context of the span is the one the step receives, so spans started by the step,
+// for example by a nested chain, are children of it. Use DoContext to make the spans children of the
+// span of the caller. nil disables tracing.
+func (fc *FunChain) WithTracer(t Tracer) *FunChain {
+	return fc.Configure(WithTracer(t))
+}
+
+// WithTracer returns an Option doing the same as FunChain.WithTracer.
+func WithTracer(t Tracer) Option {
+	return func(fc *FunChain) {
+		fc.tracer = t
+	}
+}
+
+// startSpan starts the span of st and makes its context the one of the run, the returned function ends
+// the span and restores the context of the run. If the step replaced the context, e.g. a ContextStep adding
+// baggage, its changes are kept but the span of the step is dropped from it, see spanlessContext.
+func (fc *FunChain) startSpan(rs *runState, st *step) func(err error) {
+	parent := rs.ctx
+	var (
+		spanCtx context.Context
+		key     interface{}
+		end     func(err error)
+	)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Println("Panic from tracer:", r)
+			}
+		}()
+		spanCtx, key, end = fc.tracer.StartSpan(parent, fc.stepName(st))
+	}()
+	if spanCtx == nil {
+		return nil
+	}
+	rs.ctx = spanCtx
+	return func(err error) {
+		switch rs.ctx {
+		case spanCtx:
+			rs.ctx = parent
+		case parent:
+		default:
+			rs.ctx = &spanlessContext{Context: rs.ctx, key: key, parent: parent}
+		}
+		if end == nil {
+			return
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				fmt.Println("Panic from tracer:", r)
+			}
+		}()
+		end(err)
+	}
+}
+
+// spanlessContext is a context derived by a step from the context of its span, in which the span key is
+// looked up in the context the span was started from. This drops the span, so that the spans of the next
+// steps aren't children of a finished one, and keeps every other value set by the step.
+type spanlessContext struct {
+	context.Context
+	// key is the key of the span, parent the context the span was started from.
+	key    interface{}
+	parent context.Context
+}
+
+// Value returns the value of key in the parent context for the span key, or the one set by the step.
+func (c *spanlessContext) Value(key interface{}) interface{} {
+	if key == c.key {
+		return c.parent.Value(key)
+	}
+	return c.Context.Value(key)
+}
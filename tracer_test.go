@@ -0,0 +1,115 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type spanKey struct{}
+
+type fakeSpan struct {
+	name   string
+	parent *fakeSpan
+	ended  bool
+	err    error
+}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) StartSpan(ctx context.Context, name string) (context.Context, interface{}, func(err error)) {
+	parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+	span := &fakeSpan{name: name, parent: parent}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), spanKey{}, func(err error) {
+		span.ended, span.err = true, err
+	}
+}
+
+func TestWithTracer(t *testing.T) {
+	errBoom := errors.New("boom")
+	tracer := &fakeTracer{}
+	var inSpan *fakeSpan
+	_, err := New().ThenNamed("load", func(ctx context.Context) int {
+		// 函数收到的 context 带有当前步骤的 span
+		inSpan, _ = ctx.Value(spanKey{}).(*fakeSpan)
+		return 1
+	}).ThenNamed("save", func(n int) error {
+		return errBoom
+	}).WithTracer(tracer).Do()
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected boom error, got %v", err)
+	}
+	if len(tracer.spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(tracer.spans))
+	}
+	load, save := tracer.spans[0], tracer.spans[1]
+	if load.name != "load" || !load.ended || load.err != nil {
+		t.Fatalf("unexpected span of the first step: %+v", load)
+	}
+	if save.name != "save" || !save.ended || !errors.Is(save.err, errBoom) {
+		t.Fatalf("unexpected span of the failed step: %+v", save)
+	}
+	if inSpan != load {
+		t.Fatal("function didn't receive the context of its span")
+	}
+}
+
+func TestWithTracerContextStep(t *testing.T) {
+	tracer := &fakeTracer{}
+	root := &fakeSpan{name: "root"}
+	var tag interface{}
+	ctx := context.WithValue(context.Background(), spanKey{}, root)
+	_, err := New(func() string {
+		return "v"
+	}, tagStep{}).ThenNamed("read", func(ctx context.Context, s string) {
+		tag = ctx.Value(ctxKey{})
+	}).WithTracer(tracer).DoContext(ctx)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// ContextStep 修改的 context 保留下来，但后续的 span 仍是调用方 span 的子 span
+	if tag != "v" {
+		t.Fatalf("context change of the ContextStep lost, got %v", tag)
+	}
+	if len(tracer.spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(tracer.spans))
+	}
+	for _, span := range tracer.spans {
+		if span.parent != root || !span.ended {
+			t.Fatalf("unexpected span %q: parent=%v, ended=%v", span.name, span.parent, span.ended)
+		}
+	}
+}
+
+func TestWithTracerContextStepEqualValue(t *testing.T) {
+	root := &fakeSpan{name: "root"}
+	var parents []*fakeSpan
+	// 该 tracer 在 ContextStep 的 span 中存入与 ContextStep 相同的值
+	tracer := TracerFunc(func(ctx context.Context, name string) (context.Context, interface{}, func(err error)) {
+		parent, _ := ctx.Value(spanKey{}).(*fakeSpan)
+		parents = append(parents, parent)
+		if name == "tag" {
+			ctx = context.WithValue(ctx, ctxKey{}, "v")
+		}
+		return context.WithValue(ctx, spanKey{}, &fakeSpan{name: name, parent: parent}), spanKey{}, nil
+	})
+	var result string
+	_, err := New(func() string {
+		return "v"
+	}, tagStep{}, readStep{}).WithTracer(tracer).DoContext(context.WithValue(context.Background(), spanKey{}, root), &result)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	// 只去掉 span，ContextStep 设置的值即使与 span 的 context 中的值相同也保留
+	if result != "v" {
+		t.Fatalf("context change of the ContextStep lost, got %q", result)
+	}
+	for i, parent := range parents {
+		if parent != root {
+			t.Fatalf("span %d should be a child of the caller's span, got %v", i, parent)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoChain is returned when running the zero value of Typed, which has no chain.
+var ErrNoChain = errors.New("typed chain has no chain, use Chain2, Chain3 or Pipe")
+
+// Typed is a chain taking an A and producing a B whose steps are type checked at compile time.
+// It is built with Chain2, Chain3 and Pipe and wraps a regular *FunChain, so hooks and other settings
+// can be added through Chain, and the typed chain can be used as a nested chain with Then.
+// Steps added to the underlying chain with Then are not type checked.
+// The zero value has no chain: running it fails with ErrNoChain.
+type Typed[A, B any] struct {
+	fc *FunChain
+}
+
+// Chain2 starts a typed chain with f, which turns an A into a B.
+func Chain2[A, B any](f func(A) B) Typed[A, B] {
+	return Typed[A, B]{fc: New(f)}
+}
+
+// Chain3 starts a typed chain with f and g, the output of f must be the input of g.
+func Chain3[A, B, C any](f func(A) B, g func(B) C) Typed[A, C] {
+	return Typed[A, C]{fc: New(f, g)}
+}
+
+// Pipe returns a new typed chain made of t followed by f, the output of t must be the input of f.
+// t is not modified, so it can be extended with Pipe several times, see Clone.
+// Extending the zero value of Typed returns the zero value.
+func Pipe[A, B, C any](t Typed[A, B], f func(B) C) Typed[A, C] {
+	if t.fc == nil {
+		return Typed[A, C]{}
+	}
+	return Typed[A, C]{fc: t.fc.Clone().Then(f)}
+}
+
+// Chain returns the underlying chain, e.g. to add hooks or to nest it into another chain,
+// or nil for the zero value.
+func (t Typed[A, B]) Chain() *FunChain {
+	return t.fc
+}
+
+// Run executes the chain with a as the argument of the first function and returns the output of the last one.
+func (t Typed[A, B]) Run(a A) (B, error) {
+	return t.RunContext(context.Background(), a)
+}
+
+// RunContext executes the chain like Run with the context ctx, see DoContext.
+func (t Typed[A, B]) RunContext(ctx context.Context, a A) (b B, err error) {
+	if t.fc == nil {
+		return b, ErrNoChain
+	}
+	_, result, err := t.fc.doSeeded(ctx, []interface{}{a}, nil)
+	if err != nil {
+		return b, err
+	}
+	return resultAs[B](result, 0)
+}
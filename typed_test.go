@@ -0,0 +1,84 @@
+package funchain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	parse := Chain2(strconv.Itoa)
+	chain := Pipe(Pipe(parse, strings.ToUpper), func(s string) int {
+		return len(s)
+	})
+	n, err := chain.Run(12345)
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if n != 5 {
+		t.Fatalf("unexpected result: expected 5, got %d", n)
+	}
+
+	// 同一个链可以分别延伸，互不影响
+	upper := Pipe(parse, strings.ToUpper)
+	length := Pipe(parse, func(s string) int {
+		return len(s)
+	})
+	if s, err := upper.Run(12); err != nil || s != "12" {
+		t.Fatalf("unexpected state: s=%q, err=%v", s, err)
+	}
+	if n, err := length.Run(123); err != nil || n != 3 {
+		t.Fatalf("unexpected state: n=%d, err=%v", n, err)
+	}
+	if s, err := parse.Run(1); err != nil || s != "1" {
+		t.Fatalf("unexpected state of the original chain: s=%q, err=%v", s, err)
+	}
+
+	// 与基于反射的链互通：作为嵌套链使用
+	var result string
+	_, err = New(func() int {
+		return 7
+	}).Then(Chain3(double, strconv.Itoa).Chain()).Do(&result)
+	if err != nil || result != "14" {
+		t.Fatalf("unexpected state: result=%q, err=%v", result, err)
+	}
+
+	// 经 Chain 添加的反射步骤在运行时检查类型
+	bad := Chain2(strconv.Itoa)
+	bad.Chain().Then(func(s string) int { return len(s) })
+	if _, err := bad.Run(1); err == nil {
+		t.Fatal("expected error for an output not matching B")
+	}
+}
+
+func TestTypedZero(t *testing.T) {
+	var zero Typed[int, string]
+	if _, err := zero.Run(1); !errors.Is(err, ErrNoChain) {
+		t.Fatalf("expected ErrNoChain, got %v", err)
+	}
+	// 零值延伸后仍为零值
+	piped := Pipe(zero, strings.ToUpper)
+	if _, err := piped.Run(1); !errors.Is(err, ErrNoChain) {
+		t.Fatalf("expected ErrNoChain, got %v", err)
+	}
+	if zero.Chain() != nil || piped.Chain() != nil {
+		t.Fatal("zero value should have no chain")
+	}
+}
+
+func ExamplePipe() {
+	toText := Chain2(func(n int) string {
+		return fmt.Sprint(n * 2)
+	})
+	// 第二步的参数类型必须与第一步的返回类型一致，例如
+	// Pipe(toText, func(n int) int { return n })
+	// 无法通过编译：type func(n int) int of func(n int) int {…} does not match inferred type func(string) C for func(B) C
+	withSuffix := Pipe(toText, func(s string) string {
+		return s + "!"
+	})
+	s, err := withSuffix.Run(21)
+	fmt.Println(s, err)
+	// Output: 42! <nil>
+}
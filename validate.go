@@ -0,0 +1,338 @@
+package funchain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Diagnostic is a problem found in the wiring of a chain by Validate.
+type Diagnostic struct {
+	// Step is the index of the step the problem was found at.
+	Step int
+	// Output is the position of the concerned return value of the step, -1 if not specific to a value.
+	Output int
+	// Message describes the problem.
+	Message string
+	// Err is the error the problem causes at run time, if there is a specific one, e.g. ErrCyclicChain.
+	Err error
+}
+
+// String returns the diagnostic as a readable sentence.
+func (d Diagnostic) String() string {
+	if d.Output < 0 {
+		return fmt.Sprintf("step %d: %s", d.Step, d.Message)
+	}
+	return fmt.Sprintf("step %d, output %d: %s", d.Step, d.Output, d.Message)
+}
+
+// ValidationError is returned by Validate, it lists every problem found.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+// Error joins the diagnostics.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		msgs = append(msgs, d.String())
+	}
+	return "invalid chain: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the diagnostics, so that errors.Is(err, ErrCyclicChain) reports a cyclic chain.
+func (e *ValidationError) Unwrap() []error {
+	var errs []error
+	for _, d := range e.Diagnostics {
+		if d.Err != nil {
+			errs = append(errs, d.Err)
+		}
+	}
+	return errs
+}
+
+// Validate checks the wiring of the chain without executing it and returns a *ValidationError listing
+// the problems found, or nil.
+//
+// Chains nested into themselves, directly or through other chains, are reported with a diagnostic wrapping
+// ErrCyclicChain. Functions returning more than one error are reported, since executing them fails.
+// The return types of every function are checked against the parameter types of the function reading
+// them, the first incompatible pair is reported with the indices of both steps and the type names.
+// A value of interface type is accepted for a parameter whose type implements that interface, since
+// its dynamic type is only known at run time.
+//
+// It also traces where the return values of every function go: values that no later step ever reads are
+// reported as dead outputs, since in a large or generated chain they usually reveal a wiring bug, or
+// a function taking fewer arguments than the previous one returns.
+// Steps passing their arguments on unchanged, such as Each, Delay, Tap or TapStop, are looked through.
+// The results of the last step are considered read by Do. Steps whose use of their arguments can't be
+// known in advance, such as Step implementations or ThenMapArgs, are assumed to read all of them and
+// are not type checked.
+func (fc *FunChain) Validate() error {
+	snap := fc.snapshot()
+	var diags []Diagnostic
+	if i, ok := cyclicStep(snap.steps, map[*FunChain]bool{fc.self(): true}); ok {
+		diags = append(diags, Diagnostic{Step: i, Output: -1, Message: ErrCyclicChain.Error(), Err: ErrCyclicChain})
+	}
+	for i, st := range snap.steps {
+		if plainFunc(st) && errorResults(reflect.TypeOf(st.fn)) > 1 {
+			diags = append(diags, Diagnostic{Step: i, Output: -1, Message: "returns more than one error"})
+		}
+	}
+	if d, ok := firstTypeMismatch(snap.steps); ok {
+		diags = append(diags, d)
+	}
+	for i, st := range snap.steps {
+		produced := producedCount(st, map[*FunChain]bool{})
+		if produced <= 0 {
+			continue
+		}
+		consumer := -1
+		for j := i + 1; j < len(snap.steps); j++ {
+			if !passesThrough(snap.steps[j]) {
+				consumer = j
+				break
+			}
+		}
+		if consumer < 0 {
+			// 最后的结果由 Do 返回
+			continue
+		}
+		read := consumedCount(snap.steps[consumer], map[*FunChain]bool{})
+		for out := read; read >= 0 && out < produced; out++ {
+			diags = append(diags, Diagnostic{
+				Step:    i,
+				Output:  out,
+				Message: fmt.Sprintf("dead output: never read by step %d which takes %d arguments", consumer, read),
+			})
+		}
+	}
+	if len(diags) > 0 {
+		return &ValidationError{Diagnostics: diags}
+	}
+	return nil
+}
+
+// firstTypeMismatch returns the first return value of a function that can't be passed to the function reading it.
+func firstTypeMismatch(steps []*step) (Diagnostic, bool) {
+	for i, st := range steps {
+		if !plainFunc(st) {
+			continue
+		}
+		consumer := i + 1
+		for consumer < len(steps) && passesThrough(steps[consumer]) {
+			consumer++
+		}
+		if consumer == len(steps) || !plainFunc(steps[consumer]) {
+			continue
+		}
+		outs := resultTypes(reflect.TypeOf(st.fn))
+		in := reflect.TypeOf(steps[consumer].fn)
+		first := 0
+		if in.NumIn() > 0 && in.In(0) == contextType {
+			// 第一个参数由注入的 context 占用
+			first = 1
+		}
+		for out, t := range outs {
+			param := first + out
+			var pt reflect.Type
+			switch {
+			case in.IsVariadic() && param >= in.NumIn()-1:
+				pt = in.In(in.NumIn() - 1).Elem()
+			case param < in.NumIn():
+				pt = in.In(param)
+			default:
+				// 多出的返回值作为无用输出报告
+				continue
+			}
+			if !mayAssign(t, pt) {
+				return Diagnostic{
+					Step:    i,
+					Output:  out,
+					Message: fmt.Sprintf("type mismatch: %s can't be passed to parameter %d (%s) of step %d", t, param, pt, consumer),
+				}, true
+			}
+		}
+	}
+	return Diagnostic{}, false
+}
+
+// mayAssign reports whether a value of static type t may be passed as a parameter of type param, see argValue.
+func mayAssign(t, param reflect.Type) bool {
+	if t.AssignableTo(param) || (t.ConvertibleTo(param) && !(isInteger(t.Kind()) && param.Kind() == reflect.String)) {
+		return true
+	}
+	return t.Kind() == reflect.Interface && param.Implements(t)
+}
+
+// plainFunc reports whether st calls its function with the arguments as they are.
+func plainFunc(st *step) bool {
+	if st.exec != nil || st.mapArgs != nil || st.always != nil || st.kind != "" {
+		return false
+	}
+	return isFunc(st.fn)
+}
+
+// resultTypes returns the return types of the function type t apart from the error.
+func resultTypes(t reflect.Type) []reflect.Type {
+	types := make([]reflect.Type, 0, t.NumOut())
+	for i := 0; i < t.NumOut(); i++ {
+		if !t.Out(i).Implements(errorType) {
+			types = append(types, t.Out(i))
+		}
+	}
+	return types
+}
+
+// errorResults returns how many return values of the function type t are errors.
+func errorResults(t reflect.Type) int {
+	n := 0
+	for i := 0; i < t.NumOut(); i++ {
+		if t.Out(i).Implements(errorType) {
+			n++
+		}
+	}
+	return n
+}
+
+// cyclicStep returns the index of the first step nesting one of the active chains, directly or through
+// other chains.
+func cyclicStep(steps []*step, active map[*FunChain]bool) (int, bool) {
+	for i, st := range steps {
+		for _, sub := range nestedChains(st) {
+			if active[sub] {
+				return i, true
+			}
+			active[sub] = true
+			_, found := cyclicStep(sub.snapshot().steps, active)
+			delete(active, sub)
+			if found {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// nestedChains returns the chains nested by st, including the ones of its branches.
+func nestedChains(st *step) []*FunChain {
+	var subs []*FunChain
+	if sub, ok := st.fn.(*FunChain); ok {
+		subs = append(subs, sub.self())
+	}
+	for _, b := range st.branches {
+		subs = append(subs, nestedChains(b)...)
+	}
+	return subs
+}
+
+// producedCount returns how many values st returns apart from an error, -1 if unknown.
+// visited contains the nested chains being looked into, a chain nesting itself yields -1.
+func producedCount(st *step, visited map[*FunChain]bool) int {
+	switch {
+	case st.kind == "Map" || st.kind == "FlatMap" || st.kind == "Filter" || st.kind == "Reduce" || st.kind == "Zip" || strings.HasPrefix(st.kind, "Distinct"):
+		return 1
+	case st.exec != nil || st.mapArgs != nil:
+		return -1
+	}
+	if sub, ok := st.fn.(*FunChain); ok {
+		steps, ok := enterChain(sub, visited)
+		if !ok {
+			return -1
+		}
+		defer delete(visited, sub.self())
+		return producedCount(steps[len(steps)-1], visited)
+	}
+	if !isFunc(st.fn) {
+		return -1
+	}
+	return len(resultTypes(reflect.TypeOf(st.fn)))
+}
+
+// consumedCount returns how many of the leading arguments st reads, -1 if it may read all of them.
+// visited is used as in producedCount.
+func consumedCount(st *step, visited map[*FunChain]bool) int {
+	if st.mapArgs != nil {
+		return -1
+	}
+	switch st.kind {
+	case "Map", "FlatMap", "Filter", "Reduce", "Each":
+		return 1
+	case "Zip":
+		return 2
+	case "Always":
+		return funcParams(st.fn, 1)
+	case "WithValue":
+		return consumedCount(&step{fn: st.fn}, visited)
+	case "Race", "Fork", "ForkPartial", "Parallel", "ParallelContext":
+		most := 0
+		for _, b := range st.branches {
+			n := consumedCount(b, visited)
+			if n < 0 {
+				return -1
+			}
+			if n > most {
+				most = n
+			}
+		}
+		return most
+	}
+	if strings.HasPrefix(st.kind, "Distinct") {
+		return 1
+	}
+	if st.exec != nil {
+		return -1
+	}
+	if sub, ok := st.fn.(*FunChain); ok {
+		steps, ok := enterChain(sub, visited)
+		if !ok {
+			return -1
+		}
+		defer delete(visited, sub.self())
+		return consumedCount(steps[0], visited)
+	}
+	if !isFunc(st.fn) {
+		return -1
+	}
+	if t := reflect.TypeOf(st.fn); t.NumIn() > 0 && t.In(0) == contextType {
+		// 第一个参数由注入的 context 占用
+		return funcParams(st.fn, 1)
+	}
+	return funcParams(st.fn, 0)
+}
+
+// enterChain marks sub as visited and returns its steps, ok is false if sub is already visited or empty.
+func enterChain(sub *FunChain, visited map[*FunChain]bool) (steps []*step, ok bool) {
+	sub = sub.self()
+	if visited[sub] {
+		return nil, false
+	}
+	steps = sub.snapshot().steps
+	if len(steps) == 0 {
+		return nil, false
+	}
+	visited[sub] = true
+	return steps, true
+}
+
+// funcParams returns the number of parameters of fn after skipping the first skip ones,
+// -1 for variadic functions which can take any number of arguments.
+func funcParams(fn interface{}, skip int) int {
+	t := reflect.TypeOf(fn)
+	if t.IsVariadic() {
+		return -1
+	}
+	if t.NumIn() < skip {
+		return 0
+	}
+	return t.NumIn() - skip
+}
+
+// passesThrough reports whether st passes its arguments on unchanged.
+func passesThrough(st *step) bool {
+	switch st.kind {
+	case "Each", "Delay", "Tap", "TapStop":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package funchain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateDeadOutputs(t *testing.T) {
+	produce := func() (int, string, bool, error) {
+		return 1, "a", true, nil
+	}
+	if err := New(produce, func(n int, s string, b bool) {}).Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	// 最后一步的结果由 Do 返回，不算死输出
+	if err := New(produce).Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+
+	// 穿过 Delay 之后只读取了第一个值
+	err := New(produce).Delay(time.Millisecond).Then(func(n int) int {
+		return n
+	}).Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	var outputs []int
+	for _, d := range ve.Diagnostics {
+		if d.Step != 0 {
+			t.Fatalf("unexpected step in diagnostic: %v", d)
+		}
+		outputs = append(outputs, d.Output)
+	}
+	if !reflect.DeepEqual(outputs, []int{1, 2}) {
+		t.Fatalf("unexpected dead outputs: %v", ve.Diagnostics)
+	}
+
+	// 注入的 context 不占用上游的值，可变参数读取所有值
+	chains := []*FunChain{
+		New(produce, func(ctx context.Context, n int, s string, b bool) {}),
+		New(func() (int, int) { return 1, 2 }, func(xs ...int) {}),
+		New(func() ([]int, int) { return nil, 1 }).Map(func(n int) int { return n }),
+	}
+	for i, fc := range chains {
+		err := fc.Validate()
+		if i < 2 && err != nil {
+			t.Fatalf("chain %d: unexpected validation error: %v", i, err)
+		}
+		if i == 2 && err == nil {
+			t.Fatalf("chain %d: Map only reads the slice, the int is dead", i)
+		}
+	}
+}
+
+func TestValidateTypes(t *testing.T) {
+	compatible := []*FunChain{
+		New(func() (int, error) { return 1, nil }, func(n int) string { return "" }, func(s string) {}),
+		// interface 类型的值在运行时才知道具体类型
+		New(func() interface{} { return 1 }, func(n int) {}),
+		New(func() error { return nil }, func() {}),
+		New(func() (int, int) { return 1, 2 }, func(ctx context.Context, xs ...int) {}),
+		New(func() []byte { return nil }).Each(func(b byte) {}).Then(func(b []byte) {}),
+	}
+	for i, fc := range compatible {
+		if err := fc.Validate(); err != nil {
+			t.Fatalf("chain %d: unexpected validation error: %v", i, err)
+		}
+	}
+
+	diagnostics := func(fc *FunChain) []Diagnostic {
+		var ve *ValidationError
+		if err := fc.Validate(); !errors.As(err, &ve) {
+			t.Fatalf("expected *ValidationError, got %v", err)
+		}
+		return ve.Diagnostics
+	}
+
+	// 类型不匹配，只报告第一对
+	ds := diagnostics(New(func() (int, string) { return 1, "" }, func(n int, b bool) int { return n }, func(s string) {}))
+	want := Diagnostic{Step: 0, Output: 1, Message: "type mismatch: string can't be passed to parameter 1 (bool) of step 1"}
+	if len(ds) != 1 || ds[0] != want {
+		t.Fatalf("unexpected diagnostics: %v", ds)
+	}
+
+	// 参数数量不匹配
+	ds = diagnostics(New(func() (int, int) { return 1, 2 }, func(n int) {}))
+	if len(ds) != 1 || ds[0].Step != 0 || ds[0].Output != 1 {
+		t.Fatalf("unexpected diagnostics: %v", ds)
+	}
+
+	// 多个 error 返回值
+	ds = diagnostics(New(func() (error, error) { return nil, nil }))
+	if len(ds) != 1 || ds[0].String() != "step 0: returns more than one error" {
+		t.Fatalf("unexpected diagnostics: %v", ds)
+	}
+}
+
+func TestValidateCyclic(t *testing.T) {
+	c := New(func() int {
+		return 1
+	})
+	c.Then(c)
+	err := c.Validate()
+	if !errors.Is(err, ErrCyclicChain) {
+		t.Fatalf("expected ErrCyclicChain, got %v", err)
+	}
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Diagnostics[0].Step != 1 {
+		t.Fatalf("expected the cycle at step 1, got %v", err)
+	}
+
+	// 通过另一个链间接嵌套自身
+	a := New(func() int {
+		return 1
+	})
+	b := New(a)
+	a.Race(b, func(n int) int { return n })
+	if err := a.Validate(); !errors.Is(err, ErrCyclicChain) {
+		t.Fatalf("expected ErrCyclicChain, got %v", err)
+	}
+
+	// 同一个链嵌套两次不是循环
+	inner := New(func(n int) int { return n + 1 })
+	if err := New(func() int { return 1 }, inner, inner).Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+}
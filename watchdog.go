@@ -0,0 +1,103 @@
+package funchain
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StuckFunc is called by the watchdog when a step has been running for too long.
+// step: index of the running step.
+// name: name of the running step.
+// elapsed: time since the step started.
+type StuckFunc func(step int, name string, elapsed time.Duration)
+
+type watchdogConfig struct {
+	interval time.Duration
+	onStuck  StuckFunc
+}
+
+// Watchdog reports steps that run longer than interval by calling onStuck, e.g. to log or alert on hangs.
+// onStuck is called from a background goroutine, once every interval for as long as the step keeps running.
+// The watchdog only observes, it never aborts the step. Its goroutine exits when Do returns.
+// Time is measured with the chain's clock, see WithClock.
+func (fc *FunChain) Watchdog(interval time.Duration, onStuck StuckFunc) *FunChain {
+	return fc.Configure(Watchdog(interval, onStuck))
+}
+
+// Watchdog returns an Option doing the same as FunChain.Watchdog.
+func Watchdog(interval time.Duration, onStuck StuckFunc) Option {
+	return func(fc *FunChain) {
+		if interval <= 0 || onStuck == nil {
+			fc.watchdog = nil
+			return
+		}
+		fc.watchdog = &watchdogConfig{interval: interval, onStuck: onStuck}
+	}
+}
+
+// watchdog watches the step being executed by one run of a chain.
+type watchdog struct {
+	config *watchdogConfig
+	clock  Clock
+	done   chan struct{}
+
+	mu    sync.Mutex
+	step  int
+	name  string
+	start time.Time
+}
+
+func startWatchdog(config *watchdogConfig, clock Clock) *watchdog {
+	wd := &watchdog{
+		config: config,
+		clock:  clock,
+		done:   make(chan struct{}),
+		step:   -1,
+	}
+	go wd.loop()
+	return wd
+}
+
+// enter records the start of a step.
+func (wd *watchdog) enter(step int, name string) {
+	wd.mu.Lock()
+	wd.step, wd.name, wd.start = step, name, wd.clock.Now()
+	wd.mu.Unlock()
+}
+
+// stop stops the watchdog goroutine.
+func (wd *watchdog) stop() {
+	close(wd.done)
+}
+
+func (wd *watchdog) loop() {
+	wait := wd.config.interval
+	for {
+		select {
+		case <-wd.done:
+			return
+		case <-wd.clock.After(wait):
+		}
+		wd.mu.Lock()
+		step, name, elapsed := wd.step, wd.name, wd.clock.Now().Sub(wd.start)
+		wd.mu.Unlock()
+		if step < 0 || elapsed < wd.config.interval {
+			// 当前步骤开始不久，在其满一个周期时再检查
+			wait = wd.config.interval - elapsed
+			continue
+		}
+		wd.report(step, name, elapsed)
+		wait = wd.config.interval
+	}
+}
+
+// report calls onStuck with recovery protection.
+func (wd *watchdog) report(step int, name string, elapsed time.Duration) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Panic from watchdog:", r)
+		}
+	}()
+	wd.config.onStuck(step, name, elapsed)
+}
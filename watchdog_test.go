@@ -0,0 +1,138 @@
+package funchain
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		reports []int
+		name    string
+	)
+	goroutines := runtime.NumGoroutine()
+	_, err := New(func() {
+		time.Sleep(10 * time.Millisecond)
+	}, func() {
+		time.Sleep(120 * time.Millisecond)
+	}).Watchdog(50*time.Millisecond, func(step int, n string, elapsed time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if elapsed < 50*time.Millisecond {
+			t.Errorf("reported elapsed %s is shorter than the interval", elapsed)
+		}
+		reports = append(reports, step)
+		name = n
+	}).Do()
+	if err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) == 0 {
+		t.Fatal("the slow step was not reported")
+	}
+	for _, step := range reports {
+		if step != 1 {
+			t.Fatalf("only the second step should be reported, got %v", reports)
+		}
+	}
+	if !strings.Contains(name, "TestWatchdog") {
+		t.Fatalf("unexpected step name: %s", name)
+	}
+	// 等待看门狗协程退出
+	time.Sleep(10 * time.Millisecond)
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Fatalf("watchdog goroutine leaked: %d goroutines before, %d after", goroutines, n)
+	}
+}
+
+// manualClock 是可在多个协程中使用的 Clock，时间只在调用 Advance 时前进
+type manualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []manualWaiter
+}
+
+type manualWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.waiters = append(c.waiters, manualWaiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance advances the clock by d and fires the waiters that are due.
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
+
+// waiting returns the number of pending waiters.
+func (c *manualClock) waiting() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func TestWatchdogClock(t *testing.T) {
+	clock := &manualClock{now: time.Now()}
+	type report struct {
+		step    int
+		name    string
+		elapsed time.Duration
+	}
+	reports := make(chan report, 1)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := New().ThenNamed("slow", func() {
+			close(entered)
+			<-release
+		}).WithClock(clock).Watchdog(time.Minute, func(step int, name string, elapsed time.Duration) {
+			reports <- report{step, name, elapsed}
+		}).Do()
+		errCh <- err
+	}()
+	<-entered
+	// 等待看门狗开始等待时钟
+	for clock.waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// 时钟前进一个周期后报告，耗时按链的时钟计算
+	clock.Advance(time.Minute)
+	r := <-reports
+	if r.step != 0 || r.name != "slow" || r.elapsed != time.Minute {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	close(release)
+	if err := <-errCh; err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+}
@@ -0,0 +1,45 @@
+package funchain
+
+import "fmt"
+
+// When adds functions that only run when cond returns true, e.g. to skip an upload in dry-run mode.
+// cond is called before each of the functions with its arguments. A skipped function passes its arguments
+// on unchanged to the next step and no hooks or events are fired for it.
+// Like Then, non-function values are skipped and nested chains are accepted.
+func (fc *FunChain) When(cond func(input []interface{}) bool, fns ...interface{}) *FunChain {
+	if cond == nil {
+		return fc
+	}
+	for _, fn := range fns {
+		if isStep(fn) {
+			fc.addStep(&step{fn: fn, kind: "When", cond: cond})
+		}
+	}
+	return fc
+}
+
+// Unless is the opposite of When, the functions only run when cond returns false.
+func (fc *FunChain) Unless(cond func(input []interface{}) bool, fns ...interface{}) *FunChain {
+	if cond == nil {
+		return fc
+	}
+	for _, fn := range fns {
+		if isStep(fn) {
+			fc.addStep(&step{fn: fn, kind: "Unless", cond: func(input []interface{}) bool {
+				return !cond(input)
+			}})
+		}
+	}
+	return fc
+}
+
+// runsStep reports whether the condition of st holds for args. A panicking condition skips the step.
+func (fc *FunChain) runsStep(st *step, args []interface{}) (run bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Panic from step condition:", r)
+			run = false
+		}
+	}()
+	return st.cond(append([]interface{}(nil), args...))
+}
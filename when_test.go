@@ -0,0 +1,57 @@
+package funchain
+
+import (
+	"testing"
+)
+
+func TestWhen(t *testing.T) {
+	var (
+		dryRun   bool
+		uploaded int
+		after    int
+	)
+	isDryRun := func(input []interface{}) bool {
+		return dryRun
+	}
+	upload := func(s string) string {
+		uploaded++
+		return "uploaded " + s
+	}
+	fc := New(func() string {
+		return "file"
+	}).Unless(isDryRun, upload).When(isDryRun, func(s string) string {
+		return "would upload " + s
+	}).After(func(input, output []interface{}) {
+		after++
+	})
+
+	var result string
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "uploaded file" || uploaded != 1 || after != 2 {
+		t.Fatalf("unexpected state: result=%q, uploaded=%d, after=%d", result, uploaded, after)
+	}
+
+	// 跳过的步骤不执行，也不触发 After 钩子
+	dryRun, uploaded, after = true, 0, 0
+	if _, err := fc.Do(&result); err != nil {
+		t.Fatal("Chain execution error:", err)
+	}
+	if result != "would upload file" || uploaded != 0 || after != 2 {
+		t.Fatalf("unexpected state: result=%q, uploaded=%d, after=%d", result, uploaded, after)
+	}
+
+	// 条件接收当前参数
+	var n int
+	_, err := New(func() int {
+		return 3
+	}).When(func(input []interface{}) bool {
+		return input[0].(int) > 5
+	}, func(n int) int {
+		return 0
+	}).Do(&n)
+	if err != nil || n != 3 {
+		t.Fatalf("unexpected state: n=%d, err=%v", n, err)
+	}
+}